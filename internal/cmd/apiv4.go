@@ -14,7 +14,8 @@ type Project struct {
 	Fields struct {
 		Nodes []ProjectField
 	} `graphql:"fields(first: 100)"`
-	URL githubv4.URI
+	URL             githubv4.URI
+	ViewerCanUpdate githubv4.Boolean
 }
 
 // ProjectField is a GitHub project field, see https://docs.github.com/en/graphql/reference/objects#projectv2field.
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	appIDEnvVar             = "GHH_APP_ID"
+	appPrivateKeyEnvVar     = "GHH_APP_PRIVATE_KEY"
+	appPrivateKeyPathEnvVar = "GHH_APP_PRIVATE_KEY_PATH"
+	appInstallationOwnerEnv = "GHH_APP_INSTALLATION_OWNER"
+
+	// appJWTLifetime is the lifetime of the JWT used to authenticate as the app.
+	// GitHub accepts at most 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew is subtracted from the issued-at time to allow for clock drift.
+	appJWTClockSkew = time.Minute
+)
+
+// appConfig identifies a GitHub App and holds its private key.
+type appConfig struct {
+	id         int64
+	privateKey *rsa.PrivateKey
+	// owner is the account the app is installed on, used if the command doesn't target an owner.
+	owner string
+}
+
+// loadAppConfig reads the GitHub App configuration from the environment or the profile.
+// It returns nil if no app is configured.
+func loadAppConfig(p *profile) (*appConfig, error) {
+	id := p.AppID
+	if idStr := os.Getenv(appIDEnvVar); idStr != "" {
+		var err error
+		id, err = strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", appIDEnvVar, err)
+		}
+	}
+	if id == 0 {
+		return nil, nil
+	}
+
+	var keyPEM []byte
+	switch {
+	case os.Getenv(appPrivateKeyEnvVar) != "":
+		keyPEM = []byte(os.Getenv(appPrivateKeyEnvVar))
+	case os.Getenv(appPrivateKeyPathEnvVar) != "" || p.AppPrivateKeyPath != "":
+		path := os.Getenv(appPrivateKeyPathEnvVar)
+		if path == "" {
+			path = p.AppPrivateKeyPath
+		}
+		var err error
+		keyPEM, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading app private key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("app ID is set, but no private key: set %s or %s", appPrivateKeyEnvVar, appPrivateKeyPathEnvVar)
+	}
+
+	key, err := parseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing app private key: %w", err)
+	}
+
+	owner := os.Getenv(appInstallationOwnerEnv)
+	if owner == "" {
+		owner = p.AppInstallationOwner
+	}
+
+	return &appConfig{id: id, privateKey: key, owner: owner}, nil
+}
+
+func parseRSAPrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// newAppJWT creates a JWT that authenticates as the GitHub App, see
+// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app.
+func newAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// appJWTTransport authenticates every request with a fresh app JWT.
+type appJWTTransport struct {
+	app  *appConfig
+	base http.RoundTripper
+}
+
+func (t *appJWTTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := newAppJWT(t.app.id, t.app.privateKey, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("creating app JWT: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(req)
+}
+
+// installationTokenSource exchanges the app JWT for an installation token of the owner.
+// It must be wrapped in an oauth2.ReuseTokenSource, which refreshes the token once it
+// is about to expire.
+type installationTokenSource struct {
+	ctx    context.Context
+	client *github.Client
+	app    *appConfig
+	owner  string
+
+	mux            sync.Mutex
+	installationID int64
+	permissions    *github.InstallationPermissions
+}
+
+func newInstallationTokenSource(ctx context.Context, p *profile, app *appConfig, owner string) (*installationTokenSource, error) {
+	if owner == "" {
+		owner = app.owner
+	}
+	httpClient := &http.Client{Transport: &appJWTTransport{app: app, base: http.DefaultTransport}}
+	client, err := newRESTClient(p, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &installationTokenSource{
+		ctx:    ctx,
+		client: client,
+		app:    app,
+		owner:  owner,
+	}, nil
+}
+
+// Token creates a new installation token.
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.installationID == 0 {
+		id, err := s.findInstallation()
+		if err != nil {
+			return nil, err
+		}
+		s.installationID = id
+	}
+
+	token, _, err := s.client.Apps.CreateInstallationToken(s.ctx, s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating installation token: %w", err)
+	}
+	s.permissions = token.GetPermissions()
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// Permissions returns the permissions of the last installation token created.
+func (s *installationTokenSource) Permissions() *github.InstallationPermissions {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.permissions
+}
+
+// findInstallation returns the ID of the app installation on the owner's account.
+// Without owner, the app must have exactly one installation.
+func (s *installationTokenSource) findInstallation() (int64, error) {
+	if s.owner == "" {
+		installations, _, err := s.client.Apps.ListInstallations(s.ctx, &github.ListOptions{PerPage: 2})
+		if err != nil {
+			return 0, fmt.Errorf("listing app installations: %w", err)
+		}
+		if len(installations) != 1 {
+			return 0, fmt.Errorf("app has %d installations, set %s to select one", len(installations), appInstallationOwnerEnv)
+		}
+		return installations[0].GetID(), nil
+	}
+
+	installation, _, err := s.client.Apps.FindOrganizationInstallation(s.ctx, s.owner)
+	if err == nil {
+		return installation.GetID(), nil
+	}
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusNotFound {
+		return 0, fmt.Errorf("finding app installation for %s: %w", s.owner, err)
+	}
+	installation, _, err = s.client.Apps.FindUserInstallation(s.ctx, s.owner)
+	if err != nil {
+		return 0, fmt.Errorf("finding app installation for %s: %w", s.owner, err)
+	}
+	return installation.GetID(), nil
+}
+
+// newTokenSource returns the token source for the profile. If a GitHub App is configured,
+// the source yields installation tokens for the owner that are refreshed on expiry.
+// Otherwise, the static token from getToken is used.
+func newTokenSource(ctx context.Context, p *profile, owner string, log loggerI) (oauth2.TokenSource, error) {
+	app, err := loadAppConfig(p)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		token, err := getToken(p, log)
+		if err != nil {
+			return nil, err
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), nil
+	}
+
+	src, err := newInstallationTokenSource(ctx, p, app, owner)
+	if err != nil {
+		return nil, err
+	}
+	return &appTokenSource{
+		TokenSource:  oauth2.ReuseTokenSource(nil, src),
+		installation: src,
+	}, nil
+}
+
+// appTokenSource reuses installation tokens until they expire and exposes their permissions.
+type appTokenSource struct {
+	oauth2.TokenSource
+	installation *installationTokenSource
+}
+
+// Permissions returns the permissions of the current installation token.
+func (s *appTokenSource) Permissions() *github.InstallationPermissions {
+	return s.installation.Permissions()
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestNewAppJWT(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+	now := time.Unix(1700000000, 0)
+
+	jwt, err := newAppJWT(42, key, now)
+	require.NoError(err)
+
+	parts := strings.Split(jwt, ".")
+	require.Len(parts, 3)
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig))
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(err)
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	require.NoError(json.Unmarshal(claimsJSON, &claims))
+	assert.Equal("42", claims.Iss)
+	assert.Equal(now.Add(-appJWTClockSkew).Unix(), claims.Iat)
+	assert.Equal(now.Add(appJWTLifetime).Unix(), claims.Exp)
+}
+
+func TestInstallationTokenSourceRefresh(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+
+	var issued int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/acme/installation", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "))
+		fmt.Fprint(w, `{"id": 7}`)
+	})
+	mux.HandleFunc("/api/v3/app/installations/7/access_tokens", func(w http.ResponseWriter, _ *http.Request) {
+		issued++
+		// The first token is already expired, so the second call must refresh it.
+		expiry := time.Now().Add(-time.Minute)
+		if issued > 1 {
+			expiry = time.Now().Add(time.Hour)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, issued, expiry.Format(time.RFC3339))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &profile{Host: "ghe.example.com", APIURL: server.URL + "/api/v3/"}
+	app := &appConfig{id: 1, privateKey: key}
+	src, err := newInstallationTokenSource(context.Background(), p, app, "acme")
+	require.NoError(err)
+	ts := oauth2.ReuseTokenSource(nil, src)
+
+	token, err := ts.Token()
+	require.NoError(err)
+	assert.Equal("ghs_1", token.AccessToken)
+
+	token, err = ts.Token()
+	require.NoError(err)
+	assert.Equal("ghs_2", token.AccessToken)
+
+	token, err = ts.Token()
+	require.NoError(err)
+	assert.Equal("ghs_2", token.AccessToken)
+	assert.Equal(2, issued)
+}
+
+func TestInstallationTokenSourceOwner(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+
+	installations := map[string]int{"acme": 7, "globex": 8, "default": 9}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/", func(w http.ResponseWriter, r *http.Request) {
+		owner := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v3/orgs/"), "/installation")
+		fmt.Fprintf(w, `{"id": %d}`, installations[owner])
+	})
+	mux.HandleFunc("/api/v3/app/installations/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v3/app/installations/"), "/access_tokens")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "ghs_%s", "expires_at": %q}`, id, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &profile{Host: "ghe.example.com", APIURL: server.URL + "/api/v3/"}
+	// The configured installation owner is only a fallback for commands without an owner.
+	app := &appConfig{id: 1, privateKey: key, owner: "default"}
+
+	for owner, want := range map[string]string{"acme": "ghs_7", "globex": "ghs_8", "": "ghs_9"} {
+		src, err := newInstallationTokenSource(context.Background(), p, app, owner)
+		require.NoError(err)
+		token, err := src.Token()
+		require.NoError(err)
+		assert.Equal(want, token.AccessToken, owner)
+	}
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+)
+
+const (
+	archiveRunFile  = "run.json"
+	archiveJobsFile = "jobs.json"
+	archiveLogsFile = "logs.zip"
+)
+
+// runArchive stores the files of archived workflow runs. Every run is stored
+// in the directory <owner>/<repo>/<run ID> of the archive.
+type runArchive interface {
+	// WriteFile stores the content read from r under the slash-separated name.
+	// The size is the length of the content, or -1 if it is unknown.
+	WriteFile(name string, size int64, r io.Reader) error
+	Close() error
+}
+
+// openRunArchive opens a tar.gz archive if the path ends with .tar.gz or .tgz,
+// and a directory archive otherwise.
+func openRunArchive(path string) (runArchive, error) {
+	if isTarArchive(path) {
+		return openTarArchive(path)
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirArchive{dir: path}, nil
+}
+
+func isTarArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// archiveRun exports the metadata, the jobs and the logs of the run to the archive.
+// The run metadata is written last, so runs without it are incomplete.
+func archiveRun(ctx context.Context, c *githubClient, archive runArchive, run *github.WorkflowRun) error {
+	dir := path.Join(c.owner, c.repo, strconv.FormatInt(run.GetID(), 10))
+
+	jobs, err := c.GetWorkflowJobs(ctx, run.GetID())
+	if err != nil {
+		return fmt.Errorf("listing jobs: %w", err)
+	}
+	if err := writeArchiveJSON(archive, path.Join(dir, archiveJobsFile), jobs); err != nil {
+		return err
+	}
+
+	logs, size, err := c.GetWorkflowRunLogs(ctx, run.GetID())
+	if errors.Is(err, ErrNotFound) {
+		c.logger.Debugf("logs of run %d expired, archiving without logs", run.GetID())
+	} else if err != nil {
+		return fmt.Errorf("getting logs: %w", err)
+	} else {
+		err := archive.WriteFile(path.Join(dir, archiveLogsFile), size, logs)
+		logs.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeArchiveJSON(archive, path.Join(dir, archiveRunFile), run)
+}
+
+func writeArchiveJSON(archive runArchive, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return archive.WriteFile(name, int64(len(data)), bytes.NewReader(data))
+}
+
+// dirArchive stores the files in a directory. Existing files are replaced,
+// so an interrupted archiving can be resumed.
+type dirArchive struct {
+	dir string
+}
+
+func (a *dirArchive) WriteFile(name string, size int64, r io.Reader) error {
+	target := filepath.Join(a.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp := target + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(file, r)
+	if err == nil && size >= 0 && n != size {
+		err = fmt.Errorf("writing %s: got %d of %d bytes", name, n, size)
+	}
+	if err := errors.Join(err, file.Close()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+func (a *dirArchive) Close() error {
+	return nil
+}
+
+// tarArchive stores the files in a gzip-compressed tar file. It can be written
+// concurrently, one file at a time, but not appended to, so an existing file isn't
+// overwritten.
+type tarArchive struct {
+	mux  sync.Mutex
+	file *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+}
+
+func openTarArchive(path string) (*tarArchive, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("archive %s already exists, choose a new file or a directory to resume", path)
+	} else if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &tarArchive{file: file, gz: gz, tw: tar.NewWriter(gz)}, nil
+}
+
+// WriteFile streams the content into the archive. The tar header needs the size
+// up front, so content of unknown size is buffered in a temporary file first.
+func (a *tarArchive) WriteFile(name string, size int64, r io.Reader) error {
+	if size < 0 {
+		tmp, err := os.CreateTemp("", "ghh-archive-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if size, err = io.Copy(tmp, r); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r = tmp
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}
+	if err := a.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	n, err := io.Copy(a.tw, io.LimitReader(r, size))
+	if err == nil && n < size {
+		err = fmt.Errorf("got %d of %d bytes", n, size)
+	}
+	if err != nil {
+		// Fill the entry, so the following files can still be written. The run stays
+		// incomplete, as its metadata is written last.
+		if _, padErr := io.CopyN(a.tw, zeroReader{}, size-n); padErr != nil {
+			err = errors.Join(err, padErr)
+		}
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// zeroReader reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func (a *tarArchive) Close() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return errors.Join(a.tw.Close(), a.gz.Close(), a.file.Close())
+}
+
+// walkArchive calls fn for every file of the archive at path with its slash-separated
+// name. The content of the file can be read with read while fn runs.
+func walkArchive(archivePath string, fn func(name string, read func() ([]byte, error)) error) error {
+	if !isTarArchive(archivePath) {
+		root := os.DirFS(archivePath)
+		return fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return fn(name, func() ([]byte, error) { return fs.ReadFile(root, name) })
+		})
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", archivePath, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading %s: %w", archivePath, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, func() ([]byte, error) { return io.ReadAll(tr) }); err != nil {
+			return err
+		}
+	}
+}
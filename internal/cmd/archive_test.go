@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRun(t *testing.T) {
+	testCases := []struct {
+		archive string
+	}{
+		{archive: "archive"},        // directory
+		{archive: "archive.tar.gz"}, // tar.gz
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v3/repos/owner/repo/actions/runs/1/jobs", "/api/v3/repos/owner/repo/actions/runs/2/jobs":
+					assert.Equal("all", r.URL.Query().Get("filter"))
+					fmt.Fprint(w, `{"total_count": 1, "jobs": [{"id": 10, "name": "build"}]}`)
+				case "/api/v3/repos/owner/repo/actions/runs/1/logs":
+					http.Redirect(w, r, "http://"+r.Host+"/blob/logs.zip", http.StatusFound)
+				case "/blob/logs.zip":
+					assert.Empty(r.Header.Get("Authorization"))
+					fmt.Fprint(w, "zipped logs")
+				default:
+					// Logs of run 2 expired.
+					w.WriteHeader(http.StatusGone)
+				}
+			}))
+
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, tc.archive)
+			archive, err := openRunArchive(archivePath)
+			require.NoError(err)
+			created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			for _, id := range []int64{1, 2} {
+				run := &github.WorkflowRun{
+					ID:         toPtr(id),
+					Name:       toPtr("CI"),
+					HeadBranch: toPtr("main"),
+					Conclusion: toPtr("success"),
+					CreatedAt:  &github.Timestamp{Time: created.Add(time.Duration(id) * time.Hour)},
+				}
+				require.NoError(archiveRun(context.Background(), c, archive, run))
+			}
+			require.NoError(archive.Close())
+
+			runs, err := readArchivedRuns(archivePath)
+			require.NoError(err)
+			require.Len(runs, 2)
+			assert.Equal(int64(2), runs[0].ID)
+			assert.Equal([]string{"owner/repo/2/jobs.json", "owner/repo/2/run.json"}, runs[0].Files)
+			assert.Equal(int64(1), runs[1].ID)
+			assert.Equal("owner/repo", runs[1].Repository)
+			assert.Equal("success", runs[1].Status)
+			assert.Equal([]string{"owner/repo/1/jobs.json", "owner/repo/1/logs.zip", "owner/repo/1/run.json"}, runs[1].Files)
+
+			if !isTarArchive(archivePath) {
+				logs, err := os.ReadFile(filepath.Join(archivePath, "owner", "repo", "1", archiveLogsFile))
+				require.NoError(err)
+				assert.Equal("zipped logs", string(logs))
+			}
+		})
+	}
+}
+
+func TestOpenTarArchiveDoesNotOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.tgz")
+	require.NoError(t, os.WriteFile(path, []byte("existing"), 0o644))
+
+	_, err := openRunArchive(path)
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestTarArchiveWriteFile(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "runs.tar.gz")
+	archive, err := openRunArchive(path)
+	require.NoError(err)
+
+	require.NoError(archive.WriteFile("unknown-size", -1, strings.NewReader("streamed")))
+	// A download cut short must not corrupt the files written after it.
+	assert.Error(archive.WriteFile("truncated", 10, strings.NewReader("abcd")))
+	require.NoError(archive.WriteFile("after", 5, strings.NewReader("after")))
+	require.NoError(archive.Close())
+
+	files := map[string]string{}
+	require.NoError(walkArchive(path, func(name string, read func() ([]byte, error)) error {
+		data, err := read()
+		files[name] = string(data)
+		return err
+	}))
+	assert.Equal("streamed", files["unknown-size"])
+	assert.Len(files["truncated"], 10)
+	assert.Equal("after", files["after"])
+}
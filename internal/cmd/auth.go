@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+)
+
+const (
+	scopesHeader     = "X-OAuth-Scopes"
+	expirationHeader = "github-authentication-token-expiration"
+	expirationLayout = "2006-01-02 15:04:05 MST"
+)
+
+// NewAuthCmd creates a new command group for inspecting and removing the authentication.
+func NewAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Inspect and remove the GitHub authentication",
+	}
+	cmd.AddCommand(
+		newAuthStatusCmd(),
+		newAuthLogoutCmd(),
+	)
+	return cmd
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the user, type, scopes and expiry of the token in use",
+		RunE:  authStatus,
+	}
+}
+
+func newAuthLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the stored credential of the profile",
+		RunE:  authLogout,
+	}
+}
+
+func authStatus(cmd *cobra.Command, _ []string) error {
+	p, err := resolveProfile(cmd)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile:  %s\n", p.Name())
+	fmt.Printf("Host:     %s\n", p.Host)
+
+	app, err := loadAppConfig(p)
+	if err != nil {
+		return err
+	}
+	if app != nil {
+		return appAuthStatus(cmd.Context(), p, app)
+	}
+
+	log := newLogger(false)
+	token, err := resolveToken(p, log)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Source:   %s\n", token.source)
+
+	info, err := inspectToken(cmd.Context(), p, token.value, log)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User:     %s\n", info.login)
+	fmt.Printf("Type:     %s\n", info.tokenType)
+	if info.hasScopes {
+		fmt.Printf("Scopes:   %s\n", strings.Join(info.scopes, ", "))
+	} else {
+		fmt.Println("Scopes:   not reported for this token type")
+	}
+	if info.expiry.IsZero() {
+		fmt.Println("Expires:  never")
+	} else {
+		fmt.Printf("Expires:  %s (in %s)\n", info.expiry.Local().Format(time.RFC1123), time.Until(info.expiry).Round(time.Minute))
+	}
+	return nil
+}
+
+func appAuthStatus(ctx context.Context, p *profile, app *appConfig) error {
+	fmt.Printf("Source:   GitHub App %d\n", app.id)
+
+	src, err := newInstallationTokenSource(ctx, p, app, "")
+	if err != nil {
+		return err
+	}
+	token, err := src.Token()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Type:     %s\n", tokenType(token.AccessToken))
+	fmt.Printf("Perms:    %s\n", strings.Join(permissionList(src.Permissions()), ", "))
+	fmt.Printf("Expires:  %s\n", token.Expiry.Local().Format(time.RFC1123))
+	return nil
+}
+
+func authLogout(cmd *cobra.Command, _ []string) error {
+	p, err := resolveProfile(cmd)
+	if err != nil {
+		return err
+	}
+
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	if _, ok := settings.Profiles[p.Name()]; !ok {
+		return fmt.Errorf("no credential stored for profile %q", p.Name())
+	}
+
+	if p.CredentialStore != "" {
+		store, err := newCredentialStore(p.CredentialStore)
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(p.Name()); err != nil {
+			return fmt.Errorf("removing token from %s store: %w", store.Name(), err)
+		}
+	}
+
+	// Reload, the plaintext store modifies the settings itself.
+	settings, err = loadSettings()
+	if err != nil {
+		return err
+	}
+	p = settings.Profiles[p.Name()]
+	p.CredentialStore = ""
+	p.Token = ""
+	p.AppID = 0
+	p.AppPrivateKeyPath = ""
+	p.AppInstallationOwner = ""
+	if err := settings.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed credential of profile %q (%s).\n", p.Name(), p.Host)
+	if os.Getenv(tokenEnvVar) != "" {
+		fmt.Printf("Notice that %s is still set in your environment.\n", tokenEnvVar)
+	}
+	return nil
+}
+
+// tokenInfo describes a token as reported by the API.
+type tokenInfo struct {
+	login     string
+	tokenType string
+	// hasScopes is false for token types that don't report OAuth scopes.
+	hasScopes bool
+	scopes    []string
+	// expiry is zero for tokens without expiration.
+	expiry time.Time
+}
+
+// inspectToken calls the /user endpoint with the token to check it is valid,
+// and reads the scopes and expiry from the response headers. An expiry that can't be
+// parsed is logged and left zero, as it is only informational.
+func inspectToken(ctx context.Context, p *profile, token string, log loggerI) (*tokenInfo, error) {
+	client, err := newRESTClient(p, oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	if err != nil {
+		return nil, err
+	}
+
+	user, resp, err := client.Users.Get(ctx, "")
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("token was rejected by %s: %s", p.Host, errResp.Message)
+	} else if err != nil {
+		return nil, fmt.Errorf("getting authenticated user: %w", err)
+	}
+
+	info := &tokenInfo{
+		login:     user.GetLogin(),
+		tokenType: tokenType(token),
+	}
+	if values, ok := resp.Header[http.CanonicalHeaderKey(scopesHeader)]; ok {
+		info.hasScopes = true
+		info.scopes = parseScopes(strings.Join(values, ","))
+	}
+	if expiration := resp.Header.Get(expirationHeader); expiration != "" {
+		expiry, err := time.Parse(expirationLayout, expiration)
+		if err != nil {
+			log.Warnf("ignoring token expiration %q: %s", expiration, err)
+		} else {
+			info.expiry = expiry
+		}
+	}
+	return info, nil
+}
+
+func parseScopes(header string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// tokenType derives the type of a token from its prefix, see
+// https://github.blog/2021-04-05-behind-githubs-new-authentication-token-formats/.
+func tokenType(token string) string {
+	switch {
+	case strings.HasPrefix(token, "ghp_"):
+		return "personal access token (classic)"
+	case strings.HasPrefix(token, "github_pat_"):
+		return "fine-grained personal access token"
+	case strings.HasPrefix(token, "gho_"):
+		return "OAuth access token"
+	case strings.HasPrefix(token, "ghu_"):
+		return "GitHub App user access token"
+	case strings.HasPrefix(token, "ghs_"):
+		return "GitHub App installation access token"
+	default:
+		return "unknown"
+	}
+}
+
+// permissionList returns the granted permissions as sorted "name:access" pairs.
+func permissionList(perms *github.InstallationPermissions) []string {
+	raw, err := json.Marshal(perms)
+	if err != nil {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+	var list []string
+	for name, access := range m {
+		list = append(list, name+":"+access)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectToken(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expiration := "2030-01-02 03:04:05 UTC"
+		if r.Header.Get("Authorization") == "Bearer ghp_badexpiry" {
+			expiration = "next tuesday"
+		} else if r.Header.Get("Authorization") != "Bearer ghp_valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Bad credentials"}`)
+			return
+		}
+		w.Header().Set(scopesHeader, "repo, workflow")
+		w.Header().Set(expirationHeader, expiration)
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}))
+	defer server.Close()
+	p := &profile{Host: "ghe.example.com", APIURL: server.URL + "/api/v3/"}
+
+	info, err := inspectToken(context.Background(), p, "ghp_valid", newLogger(false))
+	require.NoError(err)
+	assert.Equal("octocat", info.login)
+	assert.Equal("personal access token (classic)", info.tokenType)
+	assert.True(info.hasScopes)
+	assert.Equal([]string{"repo", "workflow"}, info.scopes)
+	assert.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), info.expiry.UTC())
+
+	// An unparseable expiration doesn't invalidate the token.
+	info, err = inspectToken(context.Background(), p, "ghp_badexpiry", newLogger(false))
+	require.NoError(err)
+	assert.Equal("octocat", info.login)
+	assert.True(info.expiry.IsZero())
+
+	_, err = inspectToken(context.Background(), p, "ghp_invalid", newLogger(false))
+	assert.ErrorContains(err, "Bad credentials")
+}
@@ -4,110 +4,347 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v61/github"
 	"golang.org/x/oauth2"
 )
 
+// downloadTimeout limits the download of a file from a pre-signed URL, including its body.
+const downloadTimeout = 10 * time.Minute
+
+// downloadClient downloads files from pre-signed URLs, which must be requested without
+// the API credentials.
+var downloadClient = &http.Client{Timeout: downloadTimeout}
+
 type githubClient struct {
 	client *github.Client
 	owner  string
 	repo   string
+	logger loggerI
+	// dryRun makes mutating methods log the change instead of doing it.
+	dryRun bool
 }
 
-func newGithubClient(ctx context.Context, owner, repo, token string) *githubClient {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(ctx, ts)
+func newGithubClient(owner, repo string, p *profile, ts oauth2.TokenSource, logger loggerI, dryRun bool) (*githubClient, error) {
+	client, err := newRESTClient(p, newHTTPClient(p, ts, logger))
+	if err != nil {
+		return nil, err
+	}
 	return &githubClient{
-		client: github.NewClient(tc),
+		client: client,
 		owner:  owner,
 		repo:   repo,
+		logger: logger,
+		dryRun: dryRun,
+	}, nil
+}
+
+// newRESTClient creates a REST client for the API of the profile's host.
+func newRESTClient(p *profile, httpClient *http.Client) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if !p.IsEnterprise() && p.APIURL == "" {
+		return client, nil
+	}
+	client, err := client.WithEnterpriseURLs(p.RESTURL(), p.UploadURL())
+	if err != nil {
+		return nil, fmt.Errorf("setting API URLs for %s: %w", p.Host, err)
 	}
+	return client, nil
 }
 
 func (c *githubClient) GetWorkflows(ctx context.Context) ([]*github.Workflow, error) {
-	opt := &github.ListOptions{PerPage: 1000}
-	var allWorkflows []*github.Workflow
-	for {
-		workflows, resp, err := c.client.Actions.ListWorkflows(ctx, c.owner, c.repo, opt)
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Workflow, *github.Response, error) {
+		workflows, resp, err := c.client.Actions.ListWorkflows(ctx, c.owner, c.repo, &opts)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		allWorkflows = append(allWorkflows, workflows.Workflows...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
-	}
-	return allWorkflows, nil
+		return workflows.Workflows, resp, nil
+	}, listConcurrency)
 }
 
 func (c *githubClient) GetWorkflowRuns(ctx context.Context, workflowID int64) ([]*github.WorkflowRun, error) {
-	opt := &github.ListWorkflowRunsOptions{
-		ListOptions: github.ListOptions{PerPage: 1000},
-	}
 	var allRuns []*github.WorkflowRun
-	for {
-		runs, resp, err := c.client.Actions.ListWorkflowRunsByID(ctx, c.owner, c.repo, workflowID, opt)
+	err := c.StreamWorkflowRuns(ctx, workflowID, nil, func(run *github.WorkflowRun) error {
+		allRuns = append(allRuns, run)
+		return nil
+	})
+	return allRuns, err
+}
+
+// StreamWorkflowRuns calls fn for every run of the workflow matching the filter, newest
+// first, while the pages of runs are still being fetched. The filter may be nil.
+func (c *githubClient) StreamWorkflowRuns(ctx context.Context, workflowID int64, filter *github.ListWorkflowRunsOptions,
+	fn func(*github.WorkflowRun) error,
+) error {
+	return paginate(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.WorkflowRun, *github.Response, error) {
+		runs, resp, err := c.client.Actions.ListWorkflowRunsByID(ctx, c.owner, c.repo, workflowID,
+			workflowRunsOptions(filter, opts))
 		if err != nil {
-			return nil, err
-		}
-		allRuns = append(allRuns, runs.WorkflowRuns...)
-		if resp.NextPage == 0 {
-			break
+			return nil, nil, err
 		}
-		opt.Page = resp.NextPage
+		return runs.WorkflowRuns, resp, nil
+	}, listConcurrency, fn)
+}
+
+// CountWorkflowRuns returns the number of runs of the workflow matching the filter.
+// The filter may be nil.
+func (c *githubClient) CountWorkflowRuns(ctx context.Context, workflowID int64, filter *github.ListWorkflowRunsOptions) (int, error) {
+	runs, _, err := c.client.Actions.ListWorkflowRunsByID(ctx, c.owner, c.repo, workflowID,
+		workflowRunsOptions(filter, github.ListOptions{PerPage: 1}))
+	if err != nil {
+		return 0, err
 	}
-	return allRuns, nil
+	return runs.GetTotalCount(), nil
 }
 
-func (c *githubClient) DeleteWorkflowRuns(ctx context.Context, runs []*github.WorkflowRun) error {
-	for _, run := range runs {
-		_, err := c.client.Actions.DeleteWorkflowRun(ctx, c.owner, c.repo, run.GetID())
+func workflowRunsOptions(filter *github.ListWorkflowRunsOptions, opts github.ListOptions) *github.ListWorkflowRunsOptions {
+	var runOpts github.ListWorkflowRunsOptions
+	if filter != nil {
+		runOpts = *filter
+	}
+	runOpts.ListOptions = opts
+	return &runOpts
+}
+
+// GetWorkflowJobs returns the jobs of a workflow run, including the ones of earlier attempts.
+func (c *githubClient) GetWorkflowJobs(ctx context.Context, runID int64) ([]*github.WorkflowJob, error) {
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.WorkflowJob, *github.Response, error) {
+		jobs, resp, err := c.client.Actions.ListWorkflowJobs(ctx, c.owner, c.repo, runID,
+			&github.ListWorkflowJobsOptions{Filter: "all", ListOptions: opts})
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
+		return jobs.Jobs, resp, nil
+	}, listConcurrency)
+}
+
+// GetWorkflowRunLogs starts the download of the zip archive with the logs of a workflow run.
+// The caller must close the returned body. The size is -1 if the server didn't send it.
+// ErrNotFound is returned if the logs don't exist anymore.
+func (c *githubClient) GetWorkflowRunLogs(ctx context.Context, runID int64) (io.ReadCloser, int64, error) {
+	logsURL, resp, err := c.client.Actions.GetWorkflowRunLogs(ctx, c.owner, c.repo, runID, 1)
+	if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+		return nil, 0, ErrNotFound
 	}
-	return nil
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting logs URL: %w", err)
+	}
+
+	// The URL is pre-signed, so it is requested without the API credentials.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsURL.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	logsResp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("downloading logs: %w", err)
+	}
+	if logsResp.StatusCode != http.StatusOK {
+		logsResp.Body.Close()
+		return nil, 0, fmt.Errorf("downloading logs: unexpected status %s", logsResp.Status)
+	}
+	return logsResp.Body, logsResp.ContentLength, nil
+}
+
+// GetDefaultBranch returns the default branch of the repository.
+func (c *githubClient) GetDefaultBranch(ctx context.Context) (string, error) {
+	repo, _, err := c.client.Repositories.Get(ctx, c.owner, c.repo)
+	if err != nil {
+		return "", fmt.Errorf("getting repository: %w", err)
+	}
+	return repo.GetDefaultBranch(), nil
 }
 
-func (c *githubClient) GetUserRepositories(ctx context.Context) ([]*github.Repository, error) {
-	opt := &github.RepositoryListByAuthenticatedUserOptions{
-		Affiliation: "owner",
-		ListOptions: github.ListOptions{PerPage: 1000},
+// FileExists reports whether the file exists in the repository at the given ref.
+func (c *githubClient) FileExists(ctx context.Context, path, ref string) (bool, error) {
+	_, _, resp, err := c.client.Repositories.GetContents(ctx, c.owner, c.repo, path,
+		&github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting %s: %w", path, err)
 	}
-	var allRepos []*github.Repository
-	for {
-		repos, resp, err := c.client.Repositories.ListByAuthenticatedUser(ctx, opt)
+	return true, nil
+}
+
+// DeleteWorkflowRun deletes a workflow run. ErrNotFound is returned if the run doesn't exist.
+func (c *githubClient) DeleteWorkflowRun(ctx context.Context, runID int64) error {
+	if c.dryRun {
+		c.logger.Infof("dry run: would delete run %d of %s/%s", runID, c.owner, c.repo)
+		return nil
+	}
+	resp, err := c.client.Actions.DeleteWorkflowRun(ctx, c.owner, c.repo, runID)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetArtifacts returns the artifacts of the repository.
+func (c *githubClient) GetArtifacts(ctx context.Context) ([]*github.Artifact, error) {
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Artifact, *github.Response, error) {
+		artifacts, resp, err := c.client.Actions.ListArtifacts(ctx, c.owner, c.repo, &opts)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		allRepos = append(allRepos, repos...)
-		if resp.NextPage == 0 {
-			break
+		return artifacts.Artifacts, resp, nil
+	}, listConcurrency)
+}
+
+// DeleteArtifact deletes an artifact. ErrNotFound is returned if the artifact doesn't exist.
+func (c *githubClient) DeleteArtifact(ctx context.Context, artifactID int64) error {
+	if c.dryRun {
+		c.logger.Infof("dry run: would delete artifact %d of %s/%s", artifactID, c.owner, c.repo)
+		return nil
+	}
+	resp, err := c.client.Actions.DeleteArtifact(ctx, c.owner, c.repo, artifactID)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetCaches returns the Actions caches of the repository. The key matches caches by
+// prefix, the ref exactly. Both may be empty.
+func (c *githubClient) GetCaches(ctx context.Context, key, ref string) ([]*github.ActionsCache, error) {
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.ActionsCache, *github.Response, error) {
+		cacheOpts := &github.ActionsCacheListOptions{ListOptions: opts}
+		if key != "" {
+			cacheOpts.Key = &key
+		}
+		if ref != "" {
+			cacheOpts.Ref = &ref
+		}
+		caches, resp, err := c.client.Actions.ListCaches(ctx, c.owner, c.repo, cacheOpts)
+		if err != nil {
+			return nil, nil, err
 		}
-		opt.Page = resp.NextPage
+		return caches.ActionsCaches, resp, nil
+	}, listConcurrency)
+}
+
+// DeleteCache deletes an Actions cache. ErrNotFound is returned if the cache doesn't exist.
+func (c *githubClient) DeleteCache(ctx context.Context, cacheID int64) error {
+	if c.dryRun {
+		c.logger.Infof("dry run: would delete cache %d of %s/%s", cacheID, c.owner, c.repo)
+		return nil
+	}
+	resp, err := c.client.Actions.DeleteCachesByID(ctx, c.owner, c.repo, cacheID)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
 	}
-	return allRepos, nil
+	return err
 }
 
-func (c *githubClient) GetUserForks(ctx context.Context) ([]*github.Repository, error) {
-	userRepos, err := c.GetUserRepositories(ctx)
+// GetPullRequest returns the pull request with the given number. ErrNotFound is returned
+// if the pull request doesn't exist.
+func (c *githubClient) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
+	pr, resp, err := c.client.PullRequests.Get(ctx, c.owner, c.repo, number)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting pull request #%d: %w", number, err)
+	}
+	return pr, nil
+}
+
+// withRepo returns a copy of the client for another repository, sharing the
+// underlying HTTP client and thereby its rate limits.
+func (c *githubClient) withRepo(owner, repo string) *githubClient {
+	cp := *c
+	cp.owner = owner
+	cp.repo = repo
+	return &cp
+}
+
+// withLogger returns a copy of the client logging to logger. The copy shares the HTTP client.
+func (c *githubClient) withLogger(logger loggerI) *githubClient {
+	cp := *c
+	cp.logger = logger
+	return &cp
+}
+
+// GetOrgRepositories returns the repositories of the organization.
+func (c *githubClient) GetOrgRepositories(ctx context.Context, org string) ([]*github.Repository, error) {
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return c.client.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{
+			ListOptions: opts,
+		})
+	}, listConcurrency)
+}
+
+// GetUserRepositories returns the repositories the authenticated user has access to with
+// the affiliation, a comma separated list of owner, collaborator and organization_member.
+func (c *githubClient) GetUserRepositories(ctx context.Context, affiliation string) ([]*github.Repository, error) {
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return c.client.Repositories.ListByAuthenticatedUser(ctx, &github.RepositoryListByAuthenticatedUserOptions{
+			Affiliation: affiliation,
+			ListOptions: opts,
+		})
+	}, listConcurrency)
+}
+
+// GetUserForks returns the forks the authenticated user has access to with the affiliation.
+func (c *githubClient) GetUserForks(ctx context.Context, affiliation string) ([]*github.Repository, error) {
+	userRepos, err := c.GetUserRepositories(ctx, affiliation)
 	if err != nil {
 		return nil, fmt.Errorf("listing user repositories: %w", err)
 	}
+	return onlyForks(userRepos), nil
+}
 
-	var userForks []*github.Repository
-	for _, repo := range userRepos {
-		if repo.Fork == nil {
-			continue
-		}
-		if *repo.Fork {
-			userForks = append(userForks, repo)
+// GetOwnerForks returns the forks owned by the user or organization. Private forks of
+// other users than the authenticated one aren't visible.
+func (c *githubClient) GetOwnerForks(ctx context.Context, owner string) ([]*github.Repository, error) {
+	user, _, err := c.client.Users.Get(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("getting owner %s: %w", owner, err)
+	}
+
+	if user.GetType() == "Organization" {
+		repos, err := collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return c.client.Repositories.ListByOrg(ctx, owner, &github.RepositoryListByOrgOptions{
+				Type:        "forks",
+				ListOptions: opts,
+			})
+		}, listConcurrency)
+		if err != nil {
+			return nil, fmt.Errorf("listing repositories of %s: %w", owner, err)
 		}
+		return onlyForks(repos), nil
+	}
+
+	// The user endpoint only lists public repositories, also for the authenticated user.
+	// Tokens of GitHub Apps have no user, so the lookup may fail.
+	if self, _, err := c.client.Users.Get(ctx, ""); err == nil && strings.EqualFold(self.GetLogin(), owner) {
+		return c.GetUserForks(ctx, "owner")
 	}
+	repos, err := collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return c.client.Repositories.ListByUser(ctx, owner, &github.RepositoryListByUserOptions{
+			Type:        "owner",
+			ListOptions: opts,
+		})
+	}, listConcurrency)
+	if err != nil {
+		return nil, fmt.Errorf("listing repositories of %s: %w", owner, err)
+	}
+	return onlyForks(repos), nil
+}
 
-	return userForks, nil
+func onlyForks(repos []*github.Repository) []*github.Repository {
+	var forks []*github.Repository
+	for _, repo := range repos {
+		if repo.GetFork() {
+			forks = append(forks, repo)
+		}
+	}
+	return forks
 }
 
 func (c *githubClient) SyncFork(ctx context.Context, repo *github.Repository, branch string) (*github.RepoMergeUpstreamResult, error) {
@@ -115,6 +352,14 @@ func (c *githubClient) SyncFork(ctx context.Context, repo *github.Repository, br
 		return nil, errors.New("repo is not a fork")
 	}
 
+	if c.dryRun {
+		c.logger.Infof("dry run: would merge upstream into %s:%s", repo.GetFullName(), branch)
+		return &github.RepoMergeUpstreamResult{
+			Message:   toPtr("dry run, nothing merged"),
+			MergeType: toPtr("none"),
+		}, nil
+	}
+
 	req := &github.RepoMergeUpstreamRequest{
 		Branch: &branch,
 	}
@@ -135,20 +380,82 @@ func (c *githubClient) SyncFork(ctx context.Context, repo *github.Repository, br
 	}
 }
 
+// GetBranch returns the branch of the repository. ErrNotFound is returned if the branch
+// doesn't exist.
 func (c *githubClient) GetBranch(ctx context.Context, repo *github.Repository, branch string) (*github.Branch, error) {
 	result, resp, err := c.client.Repositories.GetBranch(ctx, repo.GetOwner().GetLogin(), repo.GetName(), branch, 10)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("getting branch: %w", err)
 	}
+	return result, nil
+}
 
-	switch resp.StatusCode {
-	case 200:
-		return result, nil
-	case 404:
-		return nil, ErrNotFound
-	default:
-		return nil, fmt.Errorf("An unknown statuscode was returned: %s", resp.Status)
+// GetParent returns the repository the fork was created from.
+func (c *githubClient) GetParent(ctx context.Context, fork *github.Repository) (*github.Repository, error) {
+	if fork.Parent != nil {
+		return fork.Parent, nil
+	}
+	repo, _, err := c.client.Repositories.Get(ctx, fork.GetOwner().GetLogin(), fork.GetName())
+	if err != nil {
+		return nil, fmt.Errorf("getting repository: %w", err)
+	}
+	if repo.Parent == nil {
+		return nil, errors.New("repo is not a fork")
+	}
+	return repo.Parent, nil
+}
+
+// CompareCommits compares two commits of the repository or of its fork network,
+// given as branch, "owner:branch" or SHA.
+func (c *githubClient) CompareCommits(ctx context.Context, repo *github.Repository, base, head string) (*github.CommitsComparison, error) {
+	comparison, _, err := c.client.Repositories.CompareCommits(ctx, repo.GetOwner().GetLogin(), repo.GetName(), base, head, nil)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %s...%s: %w", base, head, err)
 	}
+	return comparison, nil
+}
+
+// UpdateBranch points the branch of the repository to the commit. Only fast-forward
+// updates are allowed.
+func (c *githubClient) UpdateBranch(ctx context.Context, repo *github.Repository, branch, sha string) error {
+	if c.dryRun {
+		c.logger.Infof("dry run: would update %s:%s to %s", repo.GetFullName(), branch, sha)
+		return nil
+	}
+	ref := &github.Reference{
+		Ref:    toPtr("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: &sha},
+	}
+	if _, _, err := c.client.Git.UpdateRef(ctx, repo.GetOwner().GetLogin(), repo.GetName(), ref, false); err != nil {
+		return fmt.Errorf("updating branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// GetTags returns the tags of the repository.
+func (c *githubClient) GetTags(ctx context.Context, repo *github.Repository) ([]*github.RepositoryTag, error) {
+	return collect(ctx, func(ctx context.Context, opts github.ListOptions) ([]*github.RepositoryTag, *github.Response, error) {
+		return c.client.Repositories.ListTags(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &opts)
+	}, listConcurrency)
+}
+
+// CreateTag creates a lightweight tag pointing to the commit.
+func (c *githubClient) CreateTag(ctx context.Context, repo *github.Repository, tag, sha string) error {
+	if c.dryRun {
+		c.logger.Infof("dry run: would create tag %s in %s at %s", tag, repo.GetFullName(), sha)
+		return nil
+	}
+	ref := &github.Reference{
+		Ref:    toPtr("refs/tags/" + tag),
+		Object: &github.GitObject{SHA: &sha},
+	}
+	if _, _, err := c.client.Git.CreateRef(ctx, repo.GetOwner().GetLogin(), repo.GetName(), ref); err != nil {
+		return fmt.Errorf("creating tag %s: %w", tag, err)
+	}
+	return nil
 }
 
 // ErrNotFound is returned when a resource is not found.
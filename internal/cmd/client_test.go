@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGithubClientDryRun(t *testing.T) {
+	assert := assert.New(t)
+
+	var mutations atomic.Int32
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			mutations.Add(1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	c.dryRun = true
+
+	assert.NoError(c.DeleteWorkflowRun(context.Background(), 1))
+	fork := &github.Repository{
+		Name:  toPtr("repo"),
+		Owner: &github.User{Login: toPtr("owner")},
+		Fork:  toPtr(true),
+	}
+	result, err := c.SyncFork(context.Background(), fork, "main")
+	assert.NoError(err)
+	assert.Equal("none", result.GetMergeType())
+	assert.Zero(mutations.Load())
+}
+
+// newTestGithubClient returns a client for the repository owner/repo whose API is served
+// by handler under /api/v3/. The server is closed when the test finishes.
+func newTestGithubClient(t *testing.T, handler http.Handler) *githubClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	p := &profile{Host: "ghe.example.com", APIURL: server.URL + "/api/v3/"}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+	c, err := newGithubClient("owner", "repo", p, ts, newLogger(false), false)
+	require.NoError(t, err)
+	return c
+}
@@ -11,15 +11,16 @@ import (
 type githubV4Client struct {
 	client *githubv4.Client
 	logger loggerI
+	// dryRun makes mutations log the change instead of doing it.
+	dryRun bool
 }
 
-func newGithubV4Client(ctx context.Context, token string, logger loggerI) *githubV4Client {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(ctx, ts)
-	client := githubv4.NewClient(tc)
+func newGithubV4Client(p *profile, ts oauth2.TokenSource, logger loggerI, dryRun bool) *githubV4Client {
+	client := githubv4.NewEnterpriseClient(p.GraphQLEndpoint(), newHTTPClient(p, ts, logger))
 	return &githubV4Client{
 		client: client,
 		logger: logger,
+		dryRun: dryRun,
 	}
 }
 
@@ -84,6 +85,11 @@ func (c *githubV4Client) AddProjectV2DraftIssue(ctx context.Context, input githu
 			ProjectItem ProjectItem
 		} `graphql:"addProjectV2DraftIssue(input: $input)"`
 	}
+	if c.dryRun {
+		c.logger.Infof("dry run: would add draft issue %q", input.Title)
+		c.logger.PrintJSON("dry run: would add draft issue", input)
+		return m.AddProjectV2DraftIssue.ProjectItem, nil
+	}
 
 	return m.AddProjectV2DraftIssue.ProjectItem, c.client.Mutate(ctx, &m, input, nil)
 }
@@ -130,6 +136,9 @@ func (c *githubV4Client) UpdateProjectV2ItemFieldValueInput(ctx context.Context,
 		}
 
 		c.logger.PrintJSON("update project fields input", input)
+		if c.dryRun {
+			continue
+		}
 		var m struct {
 			UpdateProjectV2ItemFieldValue struct {
 				ClientMutationID githubv4.String
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
+)
+
+// printTable prints the rows aligned in columns below the header.
+func printTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// confirmTyped asks the user to type the expected text to confirm a destructive action.
+// It fails if stdin is not a terminal, so scripts must pass '--yes' explicitly.
+func confirmTyped(action, expected string) (bool, error) {
+	if !stdinIsTerminal() {
+		return false, errors.New("stdin is not a terminal, pass '--yes' to skip the confirmation")
+	}
+	prompt := promptui.Prompt{
+		Label: fmt.Sprintf("%s? Type %q to confirm", action, expected),
+	}
+	answer, err := prompt.Run()
+	if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(answer) == expected, nil
+}
+
+// stdinIsTerminal reports whether the user can be prompted.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+const (
+	storeAuto          = "auto"
+	storeSecretService = "secret-service"
+	storeEncryptedFile = "encrypted-file"
+	storePlaintext     = "plaintext"
+
+	passphraseEnvVar       = "GHH_PASSPHRASE"
+	encryptedStoreFilePath = "ghh/credentials.enc"
+)
+
+// ErrCredentialNotFound is returned when a credential store holds no token for an account.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// credentialStore is a backend that persists tokens, keyed by account.
+// The account is the name of the profile the token belongs to.
+type credentialStore interface {
+	Name() string
+	Available() bool
+	Get(account string) (string, error)
+	Set(account, token string) error
+	Delete(account string) error
+}
+
+// newCredentialStore returns the credential store with the given name.
+func newCredentialStore(name string) (credentialStore, error) {
+	switch name {
+	case storeSecretService:
+		return &secretServiceStore{}, nil
+	case storeEncryptedFile:
+		return &encryptedFileStore{}, nil
+	case storePlaintext:
+		return &plaintextStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q", name)
+	}
+}
+
+// bestCredentialStore returns the most secure credential store available on this system.
+// The plaintext store is never chosen automatically.
+func bestCredentialStore() credentialStore {
+	if s := (&secretServiceStore{}); s.Available() {
+		return s
+	}
+	return &encryptedFileStore{}
+}
+
+// secretServiceStore stores tokens in the freedesktop Secret Service (GNOME Keyring, KWallet, ...).
+// It talks to the service over D-Bus using the secret-tool CLI shipped with libsecret.
+type secretServiceStore struct{}
+
+func (s *secretServiceStore) Name() string { return storeSecretService }
+
+func (s *secretServiceStore) Available() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (s *secretServiceStore) Get(account string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", "ghh", "account", account)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+		return "", ErrCredentialNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("looking up secret: %w", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", ErrCredentialNotFound
+	}
+	return token, nil
+}
+
+func (s *secretServiceStore) Set(account, token string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "ghh: "+account, "service", "ghh", "account", account)
+	cmd.Stdin = strings.NewReader(token)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("storing secret: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s *secretServiceStore) Delete(account string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", "ghh", "account", account)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("clearing secret: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// encryptedFileStore stores tokens in a file in the config directory, encrypted with
+// AES-GCM under a key derived from a user passphrase. It is meant for headless machines
+// without a Secret Service. The passphrase is read from GHH_PASSPHRASE or from the terminal.
+type encryptedFileStore struct {
+	passphrase []byte
+}
+
+type encryptedFile struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+const (
+	pbkdf2Iterations = 600_000
+	saltSize         = 16
+	keySize          = 32
+)
+
+func (s *encryptedFileStore) Name() string { return storeEncryptedFile }
+
+func (s *encryptedFileStore) Available() bool { return true }
+
+func (s *encryptedFileStore) Get(account string) (string, error) {
+	tokens, err := s.load(false)
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[account]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return token, nil
+}
+
+func (s *encryptedFileStore) Set(account, token string) error {
+	tokens, err := s.load(true)
+	if err != nil {
+		return err
+	}
+	tokens[account] = token
+	return s.save(tokens)
+}
+
+func (s *encryptedFileStore) Delete(account string) error {
+	tokens, err := s.load(false)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	delete(tokens, account)
+	return s.save(tokens)
+}
+
+func (s *encryptedFileStore) path() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, encryptedStoreFilePath), nil
+}
+
+// load decrypts the store. If create is set, a missing file results in an empty store.
+func (s *encryptedFileStore) load(create bool) (map[string]string, error) {
+	path, err := s.path()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, ErrCredentialNotFound
+		}
+		if err := s.readPassphrase(true); err != nil {
+			return nil, err
+		}
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := s.readPassphrase(false); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(s.passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("decrypting credentials: wrong passphrase or corrupted file")
+	}
+
+	tokens := map[string]string{}
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing decrypted credentials: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *encryptedFileStore) save(tokens map[string]string) error {
+	path, err := s.path()
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{Salt: make([]byte, saltSize)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	raw, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o600)
+}
+
+func (s *encryptedFileStore) readPassphrase(confirm bool) error {
+	if s.passphrase != nil {
+		return nil
+	}
+	if passphrase := os.Getenv(passphraseEnvVar); passphrase != "" {
+		s.passphrase = []byte(passphrase)
+		return nil
+	}
+	if !term.IsTerminal(syscall.Stdin) {
+		return fmt.Errorf("no passphrase for the encrypted credential store: set %s", passphraseEnvVar)
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase for the ghh credential store: ")
+	passphrase, err := term.ReadPassword(syscall.Stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+	if len(passphrase) == 0 {
+		return errors.New("passphrase must not be empty")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		repeated, err := term.ReadPassword(syscall.Stdin)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(passphrase, repeated) {
+			return errors.New("passphrases do not match")
+		}
+	}
+	s.passphrase = passphrase
+	return nil
+}
+
+func newGCM(passphrase, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, salt, pbkdf2Iterations, keySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// plaintextStore keeps the token unencrypted in the settings file.
+// It must be explicitly opted in to with --insecure-storage.
+type plaintextStore struct{}
+
+func (s *plaintextStore) Name() string { return storePlaintext }
+
+func (s *plaintextStore) Available() bool { return true }
+
+func (s *plaintextStore) Get(account string) (string, error) {
+	settings, err := loadSettings()
+	if err != nil {
+		return "", err
+	}
+	p, ok := settings.Profiles[account]
+	if !ok || p.Token == "" {
+		return "", ErrCredentialNotFound
+	}
+	return p.Token, nil
+}
+
+func (s *plaintextStore) Set(account, token string) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	p, ok := settings.Profiles[account]
+	if !ok {
+		return fmt.Errorf("profile %q not found", account)
+	}
+	p.Token = token
+	return settings.save()
+}
+
+func (s *plaintextStore) Delete(account string) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	p, ok := settings.Profiles[account]
+	if !ok {
+		return nil
+	}
+	p.Token = ""
+	return settings.save()
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedFileStore(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(passphraseEnvVar, "correct horse battery staple")
+
+	store := &encryptedFileStore{}
+	_, err := store.Get(defaultHost)
+	assert.ErrorIs(err, ErrCredentialNotFound)
+
+	require.NoError(store.Set(defaultHost, "ghp_secret"))
+
+	token, err := (&encryptedFileStore{}).Get(defaultHost)
+	require.NoError(err)
+	assert.Equal("ghp_secret", token)
+
+	_, err = (&encryptedFileStore{passphrase: []byte("wrong")}).Get(defaultHost)
+	assert.Error(err)
+
+	require.NoError(store.Delete(defaultHost))
+	_, err = (&encryptedFileStore{}).Get(defaultHost)
+	assert.ErrorIs(err, ErrCredentialNotFound)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// globalDefaultsSection is the section of the settings holding defaults for the global flags.
+const globalDefaultsSection = "global"
+
+// ApplyDefaults sets the flags of cmd that weren't passed on the command line from
+// environment variables and the settings file, so the precedence is flag > env > config.
+//
+// The environment variable of a flag is GHH_<COMMAND>_<FLAG>, e.g.
+// GHH_SYNC_FORKS_IGNORE_REPOS. Global flags are also read from GHH_<FLAG>, e.g. GHH_VERBOSE.
+// In the settings file, defaults are grouped by command in the "commands" section,
+// with "global" holding the defaults for global flags:
+//
+//	"commands": {
+//	  "global": {"verbose": true},
+//	  "sync-forks": {"ignore-repos": ["foo", "bar"]}
+//	}
+func ApplyDefaults(cmd *cobra.Command) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+
+	section := commandSection(cmd)
+	commandDefaults := settings.Commands[section]
+	globalDefaults := settings.Commands[globalDefaultsSection]
+	if err := checkDefaultsSection(cmd.Flags(), section, commandDefaults); err != nil {
+		return err
+	}
+	if err := checkDefaultsSection(cmd.InheritedFlags(), globalDefaultsSection, globalDefaults); err != nil {
+		return err
+	}
+
+	var applyErr error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if applyErr != nil || f.Changed || f.Name == "help" || f.Name == "version" {
+			return
+		}
+		isGlobal := cmd.InheritedFlags().Lookup(f.Name) != nil
+
+		envVars := []string{envVarName(section, f.Name)}
+		if isGlobal {
+			envVars = append(envVars, envVarName("", f.Name))
+		}
+		for _, envVar := range envVars {
+			if value, ok := os.LookupEnv(envVar); ok {
+				if err := cmd.Flags().Set(f.Name, value); err != nil {
+					applyErr = fmt.Errorf("setting flag --%s from %s: %w", f.Name, envVar, err)
+				}
+				return
+			}
+		}
+
+		if value, ok := commandDefaults[f.Name]; ok {
+			applyErr = setFlagFromConfig(cmd.Flags(), f, section, value)
+			return
+		}
+		if value, ok := globalDefaults[f.Name]; ok && isGlobal {
+			applyErr = setFlagFromConfig(cmd.Flags(), f, globalDefaultsSection, value)
+		}
+	})
+	return applyErr
+}
+
+// commandSection returns the settings section of a command, its path without the root command.
+func commandSection(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+func envVarName(section, flag string) string {
+	name := "GHH_"
+	if section != "" {
+		name += section + "_"
+	}
+	name += flag
+	return strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(name))
+}
+
+// checkDefaultsSection returns an error if the section sets defaults for unknown flags,
+// which would otherwise be silently ignored.
+func checkDefaultsSection(flags *pflag.FlagSet, section string, defaults map[string]any) error {
+	var unknown []string
+	for name := range defaults {
+		if flags.Lookup(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("settings section %q has defaults for unknown flags: %s", section, strings.Join(unknown, ", "))
+}
+
+func setFlagFromConfig(flags *pflag.FlagSet, f *pflag.Flag, section string, value any) error {
+	list, isList := value.([]any)
+	if isList {
+		values := make([]string, 0, len(list))
+		for _, v := range list {
+			values = append(values, configValueString(v))
+		}
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			if err := sliceValue.Replace(values); err != nil {
+				return fmt.Errorf("setting flag --%s from settings section %q: %w", f.Name, section, err)
+			}
+			f.Changed = true
+			return nil
+		}
+		value = strings.Join(values, ",")
+	}
+
+	if err := flags.Set(f.Name, configValueString(value)); err != nil {
+		return fmt.Errorf("setting flag --%s from settings section %q: %w", f.Name, section, err)
+	}
+	return nil
+}
+
+func configValueString(value any) string {
+	if f, ok := value.(float64); ok {
+		// JSON numbers are decoded as float64, avoid the exponent format for large integers.
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
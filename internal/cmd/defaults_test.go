@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	testCases := []struct {
+		args             []string
+		env              map[string]string
+		settings         string
+		expectedIgnore   []string
+		expectedParallel int
+		expectedVerbose  bool
+		wantErr          bool
+	}{
+		{
+			settings:         `{"commands": {"sync-forks": {"ignore-repos": ["foo", "bar"], "parallel": 4}}}`,
+			expectedIgnore:   []string{"foo", "bar"},
+			expectedParallel: 4,
+		},
+		{
+			env:              map[string]string{"GHH_SYNC_FORKS_IGNORE_REPOS": "baz"},
+			settings:         `{"commands": {"sync-forks": {"ignore-repos": ["foo", "bar"]}}}`,
+			expectedIgnore:   []string{"baz"},
+			expectedParallel: 1,
+		},
+		{
+			args:             []string{"--ignore-repos", "qux"},
+			env:              map[string]string{"GHH_SYNC_FORKS_IGNORE_REPOS": "baz"},
+			settings:         `{"commands": {"sync-forks": {"ignore-repos": ["foo", "bar"]}}}`,
+			expectedIgnore:   []string{"qux"},
+			expectedParallel: 1,
+		},
+		{
+			settings:         `{"commands": {"global": {"verbose": true}}}`,
+			expectedIgnore:   []string{},
+			expectedParallel: 1,
+			expectedVerbose:  true,
+		},
+		{
+			env:              map[string]string{"GHH_VERBOSE": "true"},
+			expectedIgnore:   []string{},
+			expectedParallel: 1,
+			expectedVerbose:  true,
+		},
+		{
+			settings: `{"commands": {"sync-forks": {"ignore-repo": ["foo"]}}}`,
+			wantErr:  true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			configDir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", configDir)
+			t.Setenv("GHH_SYNC_FORKS_IGNORE_REPOS", "")
+			os.Unsetenv("GHH_SYNC_FORKS_IGNORE_REPOS")
+			t.Setenv("GHH_VERBOSE", "")
+			os.Unsetenv("GHH_VERBOSE")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			if tc.settings != "" {
+				require.NoError(os.MkdirAll(filepath.Join(configDir, "ghh"), 0o755))
+				require.NoError(os.WriteFile(filepath.Join(configDir, configFilePath), []byte(tc.settings), 0o600))
+			}
+
+			root := &cobra.Command{Use: "ghh"}
+			root.PersistentFlags().Bool("verbose", false, "")
+			sub := &cobra.Command{Use: "sync-forks", RunE: func(*cobra.Command, []string) error { return nil }}
+			sub.Flags().StringSlice("ignore-repos", []string{}, "")
+			sub.Flags().Int("parallel", 1, "")
+			root.AddCommand(sub)
+			root.SetArgs(append([]string{"sync-forks"}, tc.args...))
+			root.PersistentPreRunE = func(c *cobra.Command, _ []string) error { return ApplyDefaults(c) }
+
+			err := root.Execute()
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			ignore, err := sub.Flags().GetStringSlice("ignore-repos")
+			require.NoError(err)
+			assert.Equal(tc.expectedIgnore, ignore)
+			parallel, err := sub.Flags().GetInt("parallel")
+			require.NoError(err)
+			assert.Equal(tc.expectedParallel, parallel)
+			verbose, err := sub.Flags().GetBool("verbose")
+			require.NoError(err)
+			assert.Equal(tc.expectedVerbose, verbose)
+		})
+	}
+}
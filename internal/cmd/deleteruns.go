@@ -1,11 +1,24 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v61/github"
+	"github.com/katexochen/ghh/internal/selector"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
 )
 
 // NewDeleteAllRunsCmd creates a new command for deleting workflow runs.
@@ -14,46 +27,751 @@ func NewDeleteAllRunsCmd() *cobra.Command {
 		Use:   "delete-all-runs",
 		Short: "Delete all workflow runs",
 		RunE:  deleteRuns,
+		Annotations: map[string]string{
+			requiredPermissionsAnnotation: "actions:write",
+		},
 	}
+	cmd.Flags().StringArray(
+		"workflow",
+		nil,
+		"Workflow to delete the runs of, by name, ID or file name such as 'ci.yml'. Can be repeated.",
+	)
+	cmd.Flags().Bool(
+		"all-workflows",
+		false,
+		"Delete the runs of all workflows",
+	)
+	cmd.Flags().BoolP(
+		"yes",
+		"y",
+		false,
+		"Don't ask for confirmation",
+	)
+	cmd.Flags().Bool(
+		"stale-workflows",
+		false,
+		"Delete the runs of all workflows that were deleted, disabled manually, "+
+			"or whose file no longer exists on the default branch",
+	)
+	cmd.MarkFlagsMutuallyExclusive("workflow", "all-workflows", "stale-workflows")
+	addRunFilterFlags(cmd)
+	cmd.Flags().StringSlice(
+		"repo",
+		nil,
+		"Repository to delete the runs of, as [HOST/]OWNER/REPO. Can be repeated. "+
+			"Defaults to the repository of the current directory.",
+	)
+	cmd.Flags().String(
+		"org",
+		"",
+		"Delete the runs of the repositories of the organization that match the repository filters",
+	)
+	selector.AddFlags(cmd.Flags())
+	cmd.Flags().String(
+		"archive",
+		"",
+		"Archive the metadata, jobs and logs of every run before deleting it, to a directory "+
+			"or a new .tar.gz file. Runs that can't be archived aren't deleted.",
+	)
+	cmd.Flags().Int(
+		"concurrency",
+		8,
+		"Number of runs deleted in parallel",
+	)
+	cmd.Flags().String(
+		"journal",
+		"",
+		"Path of the journal recording deleted runs, used to resume an interrupted deletion. "+
+			"Defaults to a file per workflow in the user cache directory.",
+	)
 	return cmd
 }
 
 func deleteRuns(cmd *cobra.Command, _ []string) error {
-	owner, repo, err := findOwnerAndRepo()
+	flags, err := parseDeleteRunsFlags(cmd)
 	if err != nil {
 		return err
 	}
+	log := newLogger(flags.verbose)
+	ctx := cmd.Context()
 
-	token, err := getToken()
+	if len(flags.repos) == 0 && flags.org == "" {
+		repo, err := findRepo(cmd, "")
+		if err != nil {
+			return err
+		}
+		flags.repos = []repoRef{repo}
+	}
+	host, err := commonHost(flags.repos)
 	if err != nil {
 		return err
 	}
-
-	c := newGithubClient(cmd.Context(), owner, repo, token)
-
-	workflows, err := c.GetWorkflows(cmd.Context())
+	p, err := resolveProfileForRepo(cmd, host)
 	if err != nil {
 		return err
 	}
+	clients := newOwnerClients(cmd, p, log, flags.dryRun)
 
-	workflow, err := selectWorkflow(workflows)
+	repos, err := findRunRepos(ctx, clients, flags, log)
 	if err != nil {
 		return err
 	}
+	multiRepo := len(repos) > 1 || flags.org != ""
+	if multiRepo && !flags.hasWorkflowSelector() {
+		return errors.New("select workflows with '--workflow', '--all-workflows' or '--stale-workflows' " +
+			"when deleting runs of multiple repositories")
+	}
+
+	var plans []*repoWorkflows
+	var results []repoRunsResult
+	for _, repo := range repos {
+		c, err := clients.forRepo(ctx, repo)
+		if err == nil {
+			var workflows []*github.Workflow
+			workflows, err = selectRepoWorkflows(ctx, c, flags, multiRepo, log)
+			if err == nil && len(workflows) > 0 {
+				plans = append(plans, &repoWorkflows{client: c, repo: repo, workflows: workflows})
+			} else if err == nil {
+				log.Debugf("%s: no workflows selected", repo)
+			}
+		}
+		if err != nil {
+			if !multiRepo || ctx.Err() != nil {
+				return err
+			}
+			log.Errorf("%s: %s", repo, err)
+			results = append(results, repoRunsResult{repo: repo, err: err})
+		}
+	}
+	if len(plans) == 0 {
+		if flags.staleWorkflows {
+			fmt.Println("No stale workflows found.")
+		} else {
+			fmt.Println("No workflows selected.")
+		}
+		return repoRunsErrors(results)
+	}
+
+	if flags.dryRun {
+		for _, plan := range plans {
+			var errs []error
+			for _, workflow := range plan.workflows {
+				if err := previewWorkflowRuns(ctx, os.Stdout, plan.client, workflow, flags.filter); err != nil {
+					if !multiRepo || ctx.Err() != nil {
+						return err
+					}
+					log.Errorf("%s: %s", plan.repo, err)
+					errs = append(errs, fmt.Errorf("workflow %q: %w", workflow.GetName(), err))
+				}
+			}
+			if len(errs) > 0 {
+				results = append(results, repoRunsResult{repo: plan.repo, workflows: len(plan.workflows), err: errors.Join(errs...)})
+			}
+		}
+		return repoRunsErrors(results)
+	}
+
+	if !flags.yes {
+		total, err := printWorkflowSummary(ctx, plans, flags.filter)
+		if err != nil {
+			return err
+		}
+		action := fmt.Sprintf("Delete %d runs", total)
+		if flags.filter.hasRetention() {
+			action = fmt.Sprintf("Delete up to %d runs", total)
+		}
+		expected := confirmationText(plans)
+		ok, err := confirmTyped(action+" of "+expected, expected)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	var archive runArchive
+	if flags.archive != "" {
+		archive, err = openRunArchive(flags.archive)
+		if err != nil {
+			return fmt.Errorf("opening archive: %w", err)
+		}
+		defer func() {
+			if err := archive.Close(); err != nil {
+				log.Errorf("closing archive: %s", err)
+			}
+		}()
+	}
+
+	for _, plan := range plans {
+		result := repoRunsResult{repo: plan.repo, workflows: len(plan.workflows)}
+		var errs []error
+		for _, workflow := range plan.workflows {
+			deleted, err := deleteWorkflowRuns(ctx, plan.client, workflow, flags, archive, log)
+			result.deleted += deleted
+			if ctx.Err() != nil {
+				return err
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("workflow %q: %w", workflow.GetName(), err))
+			}
+		}
+		result.err = errors.Join(errs...)
+		results = append(results, result)
+	}
+
+	if multiRepo {
+		if err := printRepoRunsSummary(results); err != nil {
+			return err
+		}
+	}
+	return repoRunsErrors(results)
+}
+
+// ownerClients creates one client per repository owner, as the tokens of GitHub App
+// installations are per owner. The clients of one owner share their rate limits.
+type ownerClients struct {
+	cmd     *cobra.Command
+	profile *profile
+	log     loggerI
+	dryRun  bool
+	clients map[string]*githubClient
+	// tokenSources holds the token source of each client for the preflight checks.
+	tokenSources map[string]oauth2.TokenSource
+}
+
+func newOwnerClients(cmd *cobra.Command, p *profile, log loggerI, dryRun bool) *ownerClients {
+	return &ownerClients{
+		cmd:          cmd,
+		profile:      p,
+		log:          log,
+		dryRun:       dryRun,
+		clients:      map[string]*githubClient{},
+		tokenSources: map[string]oauth2.TokenSource{},
+	}
+}
+
+// forOwner returns the client of the owner and its token source, creating them on first use.
+// Owners are compared case-insensitively like on GitHub.
+func (o *ownerClients) forOwner(ctx context.Context, owner string) (*githubClient, oauth2.TokenSource, error) {
+	key := strings.ToLower(owner)
+	c, ok := o.clients[key]
+	if !ok {
+		ts, err := newTokenSource(ctx, o.profile, owner, o.log)
+		if err != nil {
+			return nil, nil, err
+		}
+		c, err = newGithubClient(owner, "", o.profile, ts, o.log, o.dryRun)
+		if err != nil {
+			return nil, nil, err
+		}
+		o.clients[key] = c
+		o.tokenSources[key] = ts
+	}
+	return c, o.tokenSources[key], nil
+}
+
+// forRepo returns a client for the repository. The token is checked for the permissions
+// required by the command on the repository. Without repository name, the client can
+// only be used to list the repositories of the owner.
+func (o *ownerClients) forRepo(ctx context.Context, repo repoRef) (*githubClient, error) {
+	c, ts, err := o.forOwner(ctx, repo.owner)
+	if err != nil {
+		return nil, err
+	}
+	if repo.name != "" {
+		if err := preflight(ctx, o.cmd, o.profile, ts, o.log, repo); err != nil {
+			return nil, err
+		}
+	}
+	return c.withRepo(repo.owner, repo.name), nil
+}
+
+// findRunRepos returns the repositories selected by '--repo' and '--org'.
+func findRunRepos(ctx context.Context, clients *ownerClients, flags *deleteRunsFlags, log loggerI) ([]repoRef, error) {
+	repos := flags.repos
+	if flags.org != "" {
+		c, err := clients.forRepo(ctx, repoRef{owner: flags.org})
+		if err != nil {
+			return nil, err
+		}
+		orgRepos, err := c.GetOrgRepositories(ctx, flags.org)
+		if err != nil {
+			return nil, fmt.Errorf("listing repositories of %s: %w", flags.org, err)
+		}
+		for _, r := range orgRepos {
+			if !flags.selector.Match(r) {
+				log.Debugf("%s: not selected, skipping", r.GetFullName())
+				continue
+			}
+			repos = append(repos, repoRef{owner: flags.org, name: r.GetName()})
+		}
+		log.Debugf("%d repositories of %s selected", len(repos)-len(flags.repos), flags.org)
+	}
+
+	var unique []repoRef
+	seen := map[string]bool{}
+	for _, r := range repos {
+		key := strings.ToLower(r.String())
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique, nil
+}
+
+// repoWorkflows holds the workflows selected in a repository.
+type repoWorkflows struct {
+	client    *githubClient
+	repo      repoRef
+	workflows []*github.Workflow
+}
+
+// selectRepoWorkflows returns the workflows of the repository selected by the flags.
+// With multiple repositories, not every repository has every workflow, so selectors
+// without a match are ignored.
+func selectRepoWorkflows(ctx context.Context, c *githubClient, flags *deleteRunsFlags, multiRepo bool, log loggerI,
+) ([]*github.Workflow, error) {
+	workflows, err := c.GetWorkflows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing workflows: %w", err)
+	}
+	switch {
+	case flags.staleWorkflows:
+		return findStaleWorkflows(ctx, c, workflows, log)
+	case multiRepo && flags.allWorkflows:
+		return workflows, nil
+	case multiRepo:
+		var present []string
+		for _, selector := range flags.workflows {
+			for _, w := range workflows {
+				if workflowMatches(w, selector) {
+					present = append(present, selector)
+					break
+				}
+			}
+		}
+		return matchWorkflows(workflows, present)
+	default:
+		return chooseWorkflows(workflows, flags)
+	}
+}
+
+// confirmationText returns the text the user must type to confirm the deletion:
+// the repository, the common owner of the repositories, or "delete".
+func confirmationText(plans []*repoWorkflows) string {
+	if len(plans) == 1 {
+		return plans[0].repo.String()
+	}
+	owner := plans[0].repo.owner
+	for _, plan := range plans[1:] {
+		if !strings.EqualFold(plan.repo.owner, owner) {
+			return "delete"
+		}
+	}
+	return owner
+}
+
+// repoRunsResult is the outcome of deleting the runs of one repository.
+type repoRunsResult struct {
+	repo      repoRef
+	workflows int
+	deleted   int
+	err       error
+}
+
+func printRepoRunsSummary(results []repoRunsResult) error {
+	var rows [][]string
+	for _, r := range results {
+		status := "ok"
+		if r.err != nil {
+			status = "failed"
+		}
+		rows = append(rows, []string{r.repo.String(), strconv.Itoa(r.workflows), strconv.Itoa(r.deleted), status})
+	}
+	fmt.Println()
+	return printTable(os.Stdout, []string{"REPOSITORY", "WORKFLOWS", "DELETED RUNS", "STATUS"}, rows)
+}
+
+func repoRunsErrors(results []repoRunsResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.repo, r.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// chooseWorkflows returns the workflows selected by the flags. Without selector flags,
+// the user is asked to select a workflow, which requires an interactive terminal.
+func chooseWorkflows(workflows []*github.Workflow, flags *deleteRunsFlags) ([]*github.Workflow, error) {
+	switch {
+	case flags.allWorkflows:
+		if len(workflows) == 0 {
+			return nil, errors.New("repository has no workflows")
+		}
+		return workflows, nil
+	case len(flags.workflows) > 0:
+		return matchWorkflows(workflows, flags.workflows)
+	case !term.IsTerminal(int(os.Stdin.Fd())):
+		return nil, errors.New("stdin is not a terminal, select workflows with '--workflow', " +
+			"'--all-workflows' or '--stale-workflows'")
+	default:
+		workflow, err := selectWorkflow(workflows)
+		if err != nil {
+			return nil, err
+		}
+		return []*github.Workflow{workflow}, nil
+	}
+}
+
+// findStaleWorkflows returns the workflows whose runs are left over in the Actions
+// sidebar, because the workflow was deleted or disabled, or its file was removed.
+func findStaleWorkflows(ctx context.Context, c *githubClient, workflows []*github.Workflow, log loggerI) ([]*github.Workflow, error) {
+	defaultBranch, err := c.GetDefaultBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []*github.Workflow
+	var rows [][]string
+	for _, w := range workflows {
+		reason := staleWorkflowState(w)
+		if reason == "" && isWorkflowFile(w.GetPath()) {
+			exists, err := c.FileExists(ctx, w.GetPath(), defaultBranch)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				reason = fmt.Sprintf("file missing on %s", defaultBranch)
+			}
+		}
+		if reason == "" {
+			log.Debugf("workflow %q is active", w.GetName())
+			continue
+		}
+		stale = append(stale, w)
+		rows = append(rows, []string{w.GetName(), w.GetPath(), reason})
+	}
+
+	if len(stale) > 0 {
+		fmt.Printf("Found %d stale workflows in %s/%s:\n", len(stale), c.owner, c.repo)
+		if err := printTable(os.Stdout, []string{"WORKFLOW", "PATH", "REASON"}, rows); err != nil {
+			return nil, err
+		}
+	}
+	return stale, nil
+}
 
-	runs, err := c.GetWorkflowRuns(cmd.Context(), workflow.GetID())
+// staleWorkflowState returns why the workflow is stale according to its state,
+// or an empty string if the state is active.
+func staleWorkflowState(w *github.Workflow) string {
+	switch w.GetState() {
+	case "deleted":
+		return "deleted"
+	case "disabled_manually":
+		return "disabled manually"
+	default:
+		return ""
+	}
+}
+
+// isWorkflowFile reports whether the path is a workflow file in the repository.
+// Dynamic workflows, like the ones of GitHub Pages or CodeQL default setup,
+// have no file.
+func isWorkflowFile(p string) bool {
+	return strings.HasPrefix(p, ".github/workflows/")
+}
+
+// matchWorkflows returns the workflows matching the selectors. A selector matches
+// a workflow by ID, name, or path, where the path can be given without the
+// .github/workflows/ directory. Every selector must match exactly one workflow.
+func matchWorkflows(workflows []*github.Workflow, selectors []string) ([]*github.Workflow, error) {
+	var selected []*github.Workflow
+	seen := map[int64]bool{}
+	for _, selector := range selectors {
+		var matches []*github.Workflow
+		for _, w := range workflows {
+			if workflowMatches(w, selector) {
+				matches = append(matches, w)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no workflow matches %q, available workflows: %s",
+				selector, strings.Join(workflowNames(workflows), ", "))
+		case 1:
+		default:
+			var paths []string
+			for _, w := range matches {
+				paths = append(paths, w.GetPath())
+			}
+			return nil, fmt.Errorf("workflow %q is ambiguous, use one of the paths: %s", selector, strings.Join(paths, ", "))
+		}
+		if !seen[matches[0].GetID()] {
+			seen[matches[0].GetID()] = true
+			selected = append(selected, matches[0])
+		}
+	}
+	return selected, nil
+}
+
+func workflowMatches(w *github.Workflow, selector string) bool {
+	if id, err := strconv.ParseInt(selector, 10, 64); err == nil && id == w.GetID() {
+		return true
+	}
+	return w.GetName() == selector ||
+		w.GetPath() == selector ||
+		path.Base(w.GetPath()) == selector
+}
+
+// printWorkflowSummary prints the selected workflows with the number of runs matching
+// the filter. It returns the total number of runs.
+func printWorkflowSummary(ctx context.Context, plans []*repoWorkflows, filter *runFilter) (int, error) {
+	var total int
+	var rows [][]string
+	for _, plan := range plans {
+		for _, w := range plan.workflows {
+			count, err := plan.client.CountWorkflowRuns(ctx, w.GetID(), filter.listOptions())
+			if err != nil {
+				return 0, fmt.Errorf("%s: counting runs of workflow %q: %w", plan.repo, w.GetName(), err)
+			}
+			total += count
+			rows = append(rows, []string{plan.repo.String(), w.GetName(), w.GetPath(), strconv.Itoa(count)})
+		}
+	}
+	header := []string{"REPOSITORY", "WORKFLOW", "PATH", "RUNS"}
+	if filter.hasRetention() {
+		header[3] = "RUNS (BEFORE RETENTION)"
+	}
+	return total, printTable(os.Stdout, header, rows)
+}
+
+// previewWorkflowRuns prints the runs of the workflow that would be deleted. The API lists
+// at most 1000 runs per query, so if it counts more runs than it lists, the previewed runs
+// are only a part of the runs that would be deleted.
+func previewWorkflowRuns(ctx context.Context, w io.Writer, c *githubClient, workflow *github.Workflow, filter *runFilter) error {
+	// Counted before listing, so runs created meanwhile aren't mistaken for unlisted ones.
+	total, err := c.CountWorkflowRuns(ctx, workflow.GetID(), filter.listOptions())
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Deleting %d runs...\n", len(runs))
-	if err := c.DeleteWorkflowRuns(cmd.Context(), runs); err != nil {
+	retention := filter.newRetention()
+	var listed int
+	var rows [][]string
+	err = c.StreamWorkflowRuns(ctx, workflow.GetID(), filter.listOptions(), func(run *github.WorkflowRun) error {
+		listed++
+		if !filter.matches(run, retention) {
+			return nil
+		}
+		rows = append(rows, []string{
+			strconv.FormatInt(run.GetID(), 10),
+			run.GetCreatedAt().Format(time.DateTime),
+			run.GetHeadBranch(),
+			run.GetEvent(),
+			runStatus(run),
+		})
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
-	fmt.Println("Done.")
+	if listed < total {
+		fmt.Fprintf(w, "Would delete at least %d runs of workflow %q in %s/%s, the API only listed %d of %d runs:\n",
+			len(rows), workflow.GetName(), c.owner, c.repo, listed, total)
+	} else {
+		fmt.Fprintf(w, "Would delete %d runs of workflow %q in %s/%s:\n", len(rows), workflow.GetName(), c.owner, c.repo)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return printTable(w, []string{"RUN", "CREATED", "BRANCH", "EVENT", "STATUS"}, rows)
+}
 
-	return nil
+// runStatus returns the conclusion of completed runs and the status otherwise.
+func runStatus(run *github.WorkflowRun) string {
+	if run.GetConclusion() != "" {
+		return run.GetConclusion()
+	}
+	return run.GetStatus()
+}
+
+func deleteWorkflowRuns(ctx context.Context, c *githubClient, workflow *github.Workflow, flags *deleteRunsFlags,
+	archive runArchive, log loggerI,
+) (int, error) {
+	journalPath := flags.journal
+	if journalPath == "" {
+		var err error
+		journalPath, err = defaultJournalPath(c.owner, c.repo, workflow.GetID())
+		if err != nil {
+			return 0, err
+		}
+	}
+	j, err := openJournal(journalPath)
+	if err != nil {
+		return 0, fmt.Errorf("opening journal: %w", err)
+	}
+	if j.Len() > 0 {
+		fmt.Printf("Resuming from journal %s, %d runs were already deleted.\n", journalPath, j.Len())
+	}
+
+	total, err := c.CountWorkflowRuns(ctx, workflow.GetID(), flags.filter.listOptions())
+	if err != nil {
+		return 0, errors.Join(err, j.Close())
+	}
+
+	if flags.filter.hasRetention() {
+		fmt.Printf("Deleting up to %d runs of workflow %q in %s/%s...\n", total, workflow.GetName(), c.owner, c.repo)
+	} else {
+		fmt.Printf("Deleting %d runs of workflow %q in %s/%s...\n", total, workflow.GetName(), c.owner, c.repo)
+	}
+	d := &runDeleter{
+		client:   c,
+		journal:  j,
+		filter:   flags.filter,
+		archive:  archive,
+		progress: newProgressBar("Deleting runs", total),
+		log:      log,
+		failed:   map[int64]error{},
+	}
+	err = d.run(ctx, workflow.GetID(), flags.concurrency)
+	d.progress.Finish()
+
+	if ctx.Err() != nil {
+		fmt.Printf("Interrupted after deleting %d runs. Run the command again to resume.\n", d.deleted)
+		return d.deleted, errors.Join(ctx.Err(), j.Close())
+	}
+	if err != nil {
+		return d.deleted, errors.Join(err, j.Close())
+	}
+	if len(d.failed) > 0 {
+		fmt.Printf("Deleted %d runs, %d runs could not be deleted.\n", d.deleted, len(d.failed))
+		return d.deleted, errors.Join(deletionFailures("run", d.failed), j.Close())
+	}
+	if err := j.Remove(); err != nil {
+		log.Warnf("removing journal: %s", err)
+	}
+
+	fmt.Printf("Done, deleted %d runs.\n", d.deleted)
+	return d.deleted, nil
+}
+
+// runDeleter deletes workflow runs concurrently, recording deleted runs in a journal
+// and collecting failures instead of aborting on the first one.
+type runDeleter struct {
+	client  *githubClient
+	journal *journal
+	filter  *runFilter
+	// archive stores the runs before they are deleted, if set.
+	archive  runArchive
+	progress *progressBar
+	log      loggerI
+
+	mux     sync.Mutex
+	deleted int
+	failed  map[int64]error
+}
+
+// run deletes the runs of the workflow while they are listed. Deleting runs shifts
+// the later pages of the listing, so runs can be missed. The listing is repeated
+// until a pass deletes no more runs. Runs are listed newest first, so the retention
+// rules of the filter are evaluated anew in every pass.
+func (d *runDeleter) run(ctx context.Context, workflowID int64, concurrency int) error {
+	for pass := 1; ; pass++ {
+		deletedBefore := d.deletedCount()
+
+		pool := newWorkerPool(ctx, concurrency, d.delete)
+		retention := d.filter.newRetention()
+		var listed, kept int
+		err := d.client.StreamWorkflowRuns(ctx, workflowID, d.filter.listOptions(), func(run *github.WorkflowRun) error {
+			if d.journal.Contains(run.GetID()) {
+				return nil
+			}
+			if !d.filter.matches(run, retention) {
+				kept++
+				return nil
+			}
+			if d.hasFailed(run.GetID()) {
+				return nil
+			}
+			listed++
+			return pool.Submit(ctx, run)
+		})
+		pool.Wait()
+		if err != nil {
+			return err
+		}
+		if pass == 1 && kept > 0 {
+			// The total counted by the API includes the kept runs.
+			d.progress.SetTotal(listed)
+		}
+
+		if listed == 0 || d.deletedCount() == deletedBefore {
+			return nil
+		}
+		d.log.Debugf("pass %d deleted %d runs, listing remaining runs", pass, d.deletedCount()-deletedBefore)
+	}
+}
+
+func (d *runDeleter) delete(ctx context.Context, run *github.WorkflowRun) {
+	var err error
+	if d.archive != nil {
+		if err = archiveRun(ctx, d.client, d.archive, run); err != nil {
+			err = fmt.Errorf("archiving: %w", err)
+		}
+	}
+	if err == nil {
+		err = d.client.DeleteWorkflowRun(ctx, run.GetID())
+	}
+	if errors.Is(err, ErrNotFound) {
+		// Already gone, e.g. deleted by an earlier, interrupted run.
+		err = nil
+	}
+	if ctx.Err() != nil {
+		// Interrupted, the run will be deleted on resume.
+		return
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if err != nil {
+		d.log.Debugf("deleting run %d: %s", run.GetID(), err)
+		d.failed[run.GetID()] = err
+		d.progress.Increment(true)
+		return
+	}
+	if err := d.journal.Record(run.GetID()); err != nil {
+		d.log.Warnf("recording run %d in journal: %s", run.GetID(), err)
+	}
+	d.deleted++
+	d.progress.Increment(false)
+}
+
+func (d *runDeleter) deletedCount() int {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.deleted
+}
+
+func (d *runDeleter) hasFailed(runID int64) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	_, ok := d.failed[runID]
+	return ok
+}
+
+func defaultJournalPath(owner, repo string, workflowID int64) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("delete-runs_%s_%s_%d", owner, repo, workflowID)
+	return filepath.Join(cacheDir, "ghh", "journal", name), nil
 }
 
 func selectWorkflow(workflows []*github.Workflow) (*github.Workflow, error) {
@@ -76,3 +794,95 @@ func workflowNames(workflows []*github.Workflow) []string {
 	}
 	return names
 }
+
+type deleteRunsFlags struct {
+	verbose        bool
+	dryRun         bool
+	filter         *runFilter
+	workflows      []string
+	allWorkflows   bool
+	repos          []repoRef
+	org            string
+	selector       *selector.Selector
+	staleWorkflows bool
+	yes            bool
+	concurrency    int
+	journal        string
+	archive        string
+}
+
+func parseDeleteRunsFlags(cmd *cobra.Command) (*deleteRunsFlags, error) {
+	flags := &deleteRunsFlags{}
+
+	var err error
+	flags.verbose, err = cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return nil, err
+	}
+	flags.dryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
+	flags.filter, err = parseRunFilterFlags(cmd, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	flags.workflows, err = cmd.Flags().GetStringArray("workflow")
+	if err != nil {
+		return nil, err
+	}
+	flags.allWorkflows, err = cmd.Flags().GetBool("all-workflows")
+	if err != nil {
+		return nil, err
+	}
+	flags.staleWorkflows, err = cmd.Flags().GetBool("stale-workflows")
+	if err != nil {
+		return nil, err
+	}
+	flags.yes, err = cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
+	}
+	repos, err := cmd.Flags().GetStringSlice("repo")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repos {
+		repo, err := parseRepoRef(r)
+		if err != nil {
+			return nil, fmt.Errorf("parsing '--repo': %w", err)
+		}
+		flags.repos = append(flags.repos, repo)
+	}
+	flags.org, err = cmd.Flags().GetString("org")
+	if err != nil {
+		return nil, err
+	}
+	flags.selector, err = selector.FromFlags(cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+	flags.concurrency, err = cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return nil, err
+	}
+	if flags.concurrency < 1 {
+		return nil, errors.New("'--concurrency' must be at least 1")
+	}
+	flags.journal, err = cmd.Flags().GetString("journal")
+	if err != nil {
+		return nil, err
+	}
+	flags.archive, err = cmd.Flags().GetString("archive")
+	if err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+// hasWorkflowSelector reports whether the workflows are selected by flags,
+// instead of interactively.
+func (f *deleteRunsFlags) hasWorkflowSelector() bool {
+	return len(f.workflows) > 0 || f.allWorkflows || f.staleWorkflows
+}
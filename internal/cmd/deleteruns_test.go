@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/katexochen/ghh/internal/selector"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestMatchWorkflows(t *testing.T) {
+	workflows := []*github.Workflow{
+		{ID: toPtr(int64(1)), Name: toPtr("CI"), Path: toPtr(".github/workflows/ci.yml")},
+		{ID: toPtr(int64(2)), Name: toPtr("Nightly"), Path: toPtr(".github/workflows/nightly.yml")},
+		{ID: toPtr(int64(3)), Name: toPtr("Nightly"), Path: toPtr(".github/workflows/nightly-e2e.yml")},
+	}
+
+	testCases := []struct {
+		selectors []string
+		wantIDs   []int64
+		wantErr   bool
+	}{
+		{ // by name
+			selectors: []string{"CI"},
+			wantIDs:   []int64{1},
+		},
+		{ // by id
+			selectors: []string{"2"},
+			wantIDs:   []int64{2},
+		},
+		{ // by file name
+			selectors: []string{"nightly-e2e.yml"},
+			wantIDs:   []int64{3},
+		},
+		{ // by path
+			selectors: []string{".github/workflows/ci.yml"},
+			wantIDs:   []int64{1},
+		},
+		{ // multiple and duplicates
+			selectors: []string{"nightly.yml", "ci.yml", "1"},
+			wantIDs:   []int64{2, 1},
+		},
+		{ // ambiguous name
+			selectors: []string{"Nightly"},
+			wantErr:   true,
+		},
+		{ // no match
+			selectors: []string{"release.yml"},
+			wantErr:   true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			selected, err := matchWorkflows(workflows, tc.selectors)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			var ids []int64
+			for _, w := range selected {
+				ids = append(ids, w.GetID())
+			}
+			assert.Equal(tc.wantIDs, ids)
+		})
+	}
+}
+
+func TestFindStaleWorkflows(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo":
+			fmt.Fprint(w, `{"default_branch": "main"}`)
+		case "/api/v3/repos/owner/repo/contents/.github/workflows/ci.yml":
+			assert.Equal("main", r.URL.Query().Get("ref"))
+			fmt.Fprint(w, `{"type": "file", "name": "ci.yml"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+
+	workflows := []*github.Workflow{
+		{ID: toPtr(int64(1)), State: toPtr("active"), Path: toPtr(".github/workflows/ci.yml")},
+		{ID: toPtr(int64(2)), State: toPtr("active"), Path: toPtr(".github/workflows/removed.yml")},
+		{ID: toPtr(int64(3)), State: toPtr("deleted"), Path: toPtr(".github/workflows/old.yml")},
+		{ID: toPtr(int64(4)), State: toPtr("disabled_manually"), Path: toPtr(".github/workflows/ci.yml")},
+		{ID: toPtr(int64(5)), State: toPtr("active"), Path: toPtr("dynamic/pages/pages-build-deployment")},
+	}
+
+	stale, err := findStaleWorkflows(context.Background(), c, workflows, newLogger(false))
+	require.NoError(err)
+	var ids []int64
+	for _, w := range stale {
+		ids = append(ids, w.GetID())
+	}
+	assert.Equal([]int64{2, 3, 4}, ids)
+}
+
+func TestConfirmationText(t *testing.T) {
+	plan := func(owner, name string) *repoWorkflows {
+		return &repoWorkflows{repo: repoRef{owner: owner, name: name}}
+	}
+
+	testCases := []struct {
+		plans []*repoWorkflows
+		want  string
+	}{
+		{ // single repository
+			plans: []*repoWorkflows{plan("org", "a")},
+			want:  "org/a",
+		},
+		{ // common owner
+			plans: []*repoWorkflows{plan("org", "a"), plan("Org", "b")},
+			want:  "org",
+		},
+		{ // different owners
+			plans: []*repoWorkflows{plan("org", "a"), plan("user", "b")},
+			want:  "delete",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.want, confirmationText(tc.plans))
+		})
+	}
+}
+
+func TestRunDeleter(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	var mux sync.Mutex
+	existing := []int64{1, 2, 3, 4, 5}
+	deleteCalls := map[int64]int{}
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if r.URL.Path == "/api/v3/repos/owner/repo/actions/workflows/7/runs" {
+			// Return at most three runs, so the runs behind them are only found by another pass.
+			var runs []string
+			for _, id := range existing[:min(3, len(existing))] {
+				runs = append(runs, fmt.Sprintf(`{"id": %d}`, id))
+			}
+			fmt.Fprintf(w, `{"total_count": %d, "workflow_runs": [%s]}`, len(existing), strings.Join(runs, ","))
+			return
+		}
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/owner/repo/actions/runs/"), 10, 64)
+		if r.Method != http.MethodDelete || err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deleteCalls[id]++
+		switch id {
+		case 3:
+			// Already deleted, but still listed.
+			existing = slices.DeleteFunc(existing, func(e int64) bool { return e == id })
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		case 4:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			existing = slices.DeleteFunc(existing, func(e int64) bool { return e == id })
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	// Don't wait for the retries of the failing run. The transport is shared by the clients
+	// of the host, so it is restored afterwards.
+	transport := c.client.Client().Transport.(*oauth2.Transport).Base.(*rateLimitTransport)
+	sleep := transport.sleep
+	transport.sleep = func(context.Context, time.Duration) error { return nil }
+	t.Cleanup(func() { transport.sleep = sleep })
+
+	// Run 1 was deleted by an interrupted earlier run, but is still listed.
+	journalPath := filepath.Join(t.TempDir(), "journal")
+	j, err := openJournal(journalPath)
+	require.NoError(err)
+	require.NoError(j.Record(1))
+	require.NoError(j.Close())
+	j, err = openJournal(journalPath)
+	require.NoError(err)
+	defer j.Close()
+
+	d := &runDeleter{
+		client:   c,
+		journal:  j,
+		filter:   &runFilter{},
+		progress: newProgressBar("Deleting runs", len(existing)),
+		log:      newLogger(false),
+		failed:   map[int64]error{},
+	}
+	require.NoError(d.run(context.Background(), 7, 2))
+
+	assert.Equal(3, d.deleted)
+	require.Len(d.failed, 1)
+	assert.ErrorContains(d.failed[4], "500")
+	assert.Zero(deleteCalls[1])
+	assert.Equal(1, deleteCalls[2])
+	assert.Equal(1, deleteCalls[5])
+	assert.Equal(1+defaultMaxRetries, deleteCalls[4], "the failing run is retried by the transport, but not by another pass")
+	for _, id := range []int64{1, 2, 3, 5} {
+		assert.True(j.Contains(id), id)
+	}
+	assert.False(j.Contains(4))
+	assert.Equal([]int64{1, 4}, existing)
+}
+
+func TestFindRunRepos(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/orgs/org/repos" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `[{"name": "a", "full_name": "org/a"}, {"name": "old", "full_name": "org/old", "archived": true},
+			{"name": "c", "full_name": "org/c"}]`)
+	}))
+	clients := &ownerClients{
+		cmd:          &cobra.Command{},
+		log:          newLogger(false),
+		clients:      map[string]*githubClient{"org": c},
+		tokenSources: map[string]oauth2.TokenSource{"org": oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})},
+	}
+	flags := &deleteRunsFlags{
+		repos:    []repoRef{{owner: "Org", name: "A"}, {owner: "other", name: "repo"}},
+		org:      "org",
+		selector: &selector.Selector{Archived: selector.Exclude},
+	}
+
+	repos, err := findRunRepos(context.Background(), clients, flags, newLogger(false))
+	require.NoError(err)
+	assert.Equal([]repoRef{{owner: "Org", name: "A"}, {owner: "other", name: "repo"}, {owner: "org", name: "c"}}, repos)
+}
+
+func TestSelectRepoWorkflows(t *testing.T) {
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/org/both/actions/workflows":
+			fmt.Fprint(w, `{"total_count": 2, "workflows": [{"id": 1, "name": "CI", "path": ".github/workflows/ci.yml"},
+				{"id": 2, "name": "Release", "path": ".github/workflows/release.yml"}]}`)
+		case "/api/v3/repos/org/ci/actions/workflows":
+			fmt.Fprint(w, `{"total_count": 1, "workflows": [{"id": 3, "name": "CI", "path": ".github/workflows/ci.yml"}]}`)
+		case "/api/v3/repos/org/none/actions/workflows":
+			fmt.Fprint(w, `{"total_count": 0, "workflows": []}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	testCases := []struct {
+		repo      string
+		workflows []string
+		multiRepo bool
+		wantIDs   []int64
+		wantErr   bool
+	}{
+		{ // all selected workflows present
+			repo:      "both",
+			workflows: []string{"ci.yml", "release.yml"},
+			multiRepo: true,
+			wantIDs:   []int64{1, 2},
+		},
+		{ // selected workflow missing in some repositories
+			repo:      "ci",
+			workflows: []string{"ci.yml", "release.yml"},
+			multiRepo: true,
+			wantIDs:   []int64{3},
+		},
+		{ // no selected workflow present
+			repo:      "none",
+			workflows: []string{"ci.yml"},
+			multiRepo: true,
+		},
+		{ // missing workflow in single repository
+			repo:      "ci",
+			workflows: []string{"release.yml"},
+			wantErr:   true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			flags := &deleteRunsFlags{workflows: tc.workflows}
+			workflows, err := selectRepoWorkflows(context.Background(), c.withRepo("org", tc.repo), flags, tc.multiRepo, newLogger(false))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			var ids []int64
+			for _, w := range workflows {
+				ids = append(ids, w.GetID())
+			}
+			assert.Equal(tc.wantIDs, ids)
+		})
+	}
+}
+
+func TestPreviewWorkflowRuns(t *testing.T) {
+	testCases := []struct {
+		totalCount int
+		wantHeader string
+	}{
+		{ // all runs listed
+			totalCount: 2,
+			wantHeader: `Would delete 1 runs of workflow "CI" in owner/repo:`,
+		},
+		{ // API caps the listed runs
+			totalCount: 1500,
+			wantHeader: `Would delete at least 1 runs of workflow "CI" in owner/repo, the API only listed 2 of 1500 runs:`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v3/repos/owner/repo/actions/workflows/1/runs" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprintf(w, `{"total_count": %d, "workflow_runs": [
+					{"id": 11, "head_branch": "main", "event": "push", "status": "completed", "conclusion": "success"},
+					{"id": 12, "head_branch": "main", "event": "push", "status": "completed", "conclusion": "success"}]}`,
+					tc.totalCount)
+			}))
+			workflow := &github.Workflow{ID: github.Int64(1), Name: github.String("CI")}
+
+			var out strings.Builder
+			require.NoError(previewWorkflowRuns(context.Background(), &out, c, workflow, &runFilter{keepLast: 1}))
+			header, table, _ := strings.Cut(out.String(), "\n")
+			assert.Equal(tc.wantHeader, header)
+			assert.Contains(table, "12")
+			assert.NotContains(table, "11")
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/google/go-github/v61/github"
+)
+
+// Ways a fork branch can be synced with its upstream branch.
+const (
+	syncUpToDate       = "up-to-date"
+	syncFastForward    = "fast-forward"
+	syncMerge          = "merge"
+	syncLikelyConflict = "likely conflict"
+	syncDiverged       = "diverged, can't fast-forward"
+)
+
+// forkStatus describes how a fork branch relates to its upstream branch in the parent.
+type forkStatus struct {
+	upstream string
+	// ahead is the number of commits of the fork missing upstream, i.e. its own patches.
+	ahead int
+	// behind is the number of upstream commits missing in the fork.
+	behind int
+	sync   string
+	// conflicts are the files changed both in the fork and upstream since they diverged.
+	conflicts []string
+}
+
+// getForkStatus compares the branch of the fork with its upstream branch in the parent.
+func getForkStatus(ctx context.Context, c *githubClient, fork *github.Repository, branch branchMapping) (*forkStatus, error) {
+	parent, err := c.GetParent(ctx, fork)
+	if err != nil {
+		return nil, err
+	}
+	base := parent.GetOwner().GetLogin() + ":" + branch.upstream
+	head := fork.GetOwner().GetLogin() + ":" + branch.fork
+
+	forkChanges, err := c.CompareCommits(ctx, fork, base, head)
+	if err != nil {
+		return nil, err
+	}
+	status := &forkStatus{
+		upstream: parent.GetFullName() + ":" + branch.upstream,
+		ahead:    forkChanges.GetAheadBy(),
+		behind:   forkChanges.GetBehindBy(),
+	}
+
+	switch {
+	case status.behind == 0:
+		status.sync = syncUpToDate
+	case status.ahead == 0:
+		status.sync = syncFastForward
+	case branch.fastForwardOnly():
+		// The branch is only fast-forwarded, see fastForwardBranch.
+		status.sync = syncDiverged
+	default:
+		// The fork carries own patches. The comparison lists the files changed since the
+		// merge base, so the reverse comparison lists the files changed upstream.
+		upstreamChanges, err := c.CompareCommits(ctx, fork, head, base)
+		if err != nil {
+			return nil, err
+		}
+		status.conflicts = overlappingFiles(forkChanges.Files, upstreamChanges.Files)
+		status.sync = syncMerge
+		if len(status.conflicts) > 0 {
+			status.sync = syncLikelyConflict
+		}
+	}
+	return status, nil
+}
+
+// overlappingFiles returns the sorted paths changed in both lists of files.
+// Renamed files are identified by their old and new path.
+func overlappingFiles(a, b []*github.CommitFile) []string {
+	paths := map[string]bool{}
+	for _, f := range a {
+		paths[f.GetFilename()] = true
+		if f.GetPreviousFilename() != "" {
+			paths[f.GetPreviousFilename()] = true
+		}
+	}
+	overlap := map[string]bool{}
+	for _, f := range b {
+		for _, name := range []string{f.GetFilename(), f.GetPreviousFilename()} {
+			if name != "" && paths[name] {
+				overlap[name] = true
+			}
+		}
+	}
+	var files []string
+	for name := range overlap {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// forkReportRow is a row of the report printed by 'sync-forks --report'.
+func forkReportRow(fork string, branch string, status *forkStatus) []string {
+	sync := status.sync
+	if len(status.conflicts) > 0 {
+		sync = fmt.Sprintf("%s (%d files)", sync, len(status.conflicts))
+	}
+	return []string{
+		fork,
+		branch,
+		status.upstream,
+		strconv.Itoa(status.ahead),
+		strconv.Itoa(status.behind),
+		sync,
+	}
+}
+
+func printForkReport(w io.Writer, rows [][]string) error {
+	return printTable(w, []string{"FORK", "BRANCH", "UPSTREAM", "AHEAD", "BEHIND", "SYNC"}, rows)
+}
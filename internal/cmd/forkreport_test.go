@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlappingFiles(t *testing.T) {
+	testCases := []struct {
+		fork     []*github.CommitFile
+		upstream []*github.CommitFile
+		want     []string
+	}{
+		{ // no overlap
+			fork:     []*github.CommitFile{{Filename: toPtr("a.go")}},
+			upstream: []*github.CommitFile{{Filename: toPtr("b.go")}},
+		},
+		{ // overlap
+			fork:     []*github.CommitFile{{Filename: toPtr("b.go")}, {Filename: toPtr("a.go")}},
+			upstream: []*github.CommitFile{{Filename: toPtr("a.go")}, {Filename: toPtr("b.go")}, {Filename: toPtr("c.go")}},
+			want:     []string{"a.go", "b.go"},
+		},
+		{ // renamed in fork
+			fork:     []*github.CommitFile{{Filename: toPtr("new.go"), PreviousFilename: toPtr("old.go")}},
+			upstream: []*github.CommitFile{{Filename: toPtr("old.go")}},
+			want:     []string{"old.go"},
+		},
+		{ // renamed upstream
+			fork:     []*github.CommitFile{{Filename: toPtr("old.go")}},
+			upstream: []*github.CommitFile{{Filename: toPtr("new.go"), PreviousFilename: toPtr("old.go")}},
+			want:     []string{"old.go"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.want, overlappingFiles(tc.fork, tc.upstream))
+		})
+	}
+}
+
+func TestGetForkStatus(t *testing.T) {
+	// comparisons maps "base...head" to the comparison returned by the fake API.
+	main := branchMapping{fork: "main", upstream: "main"}
+	testCases := []struct {
+		branch      branchMapping
+		comparisons map[string]string
+		want        forkStatus
+	}{
+		{ // up to date
+			branch: main,
+			comparisons: map[string]string{
+				"upstream:main...me:main": `{"ahead_by": 2, "behind_by": 0}`,
+			},
+			want: forkStatus{upstream: "upstream/repo:main", ahead: 2, sync: syncUpToDate},
+		},
+		{ // fast-forward
+			branch: main,
+			comparisons: map[string]string{
+				"upstream:main...me:main": `{"ahead_by": 0, "behind_by": 5}`,
+			},
+			want: forkStatus{upstream: "upstream/repo:main", behind: 5, sync: syncFastForward},
+		},
+		{ // merge
+			branch: main,
+			comparisons: map[string]string{
+				"upstream:main...me:main": `{"ahead_by": 1, "behind_by": 5, "files": [{"filename": "patch.go"}]}`,
+				"me:main...upstream:main": `{"ahead_by": 5, "behind_by": 1, "files": [{"filename": "main.go"}]}`,
+			},
+			want: forkStatus{upstream: "upstream/repo:main", ahead: 1, behind: 5, sync: syncMerge},
+		},
+		{ // likely conflict
+			branch: main,
+			comparisons: map[string]string{
+				"upstream:main...me:main": `{"ahead_by": 1, "behind_by": 5, "files": [{"filename": "main.go"}]}`,
+				"me:main...upstream:main": `{"ahead_by": 5, "behind_by": 1, "files": [{"filename": "main.go"}]}`,
+			},
+			want: forkStatus{
+				upstream:  "upstream/repo:main",
+				ahead:     1,
+				behind:    5,
+				sync:      syncLikelyConflict,
+				conflicts: []string{"main.go"},
+			},
+		},
+		{ // different branch names
+			branch: branchMapping{fork: "main", upstream: "master"},
+			comparisons: map[string]string{
+				"upstream:master...me:main": `{"ahead_by": 1, "behind_by": 5}`,
+			},
+			want: forkStatus{upstream: "upstream/repo:master", ahead: 1, behind: 5, sync: syncDiverged},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/api/v3/repos/me/repo":
+					fmt.Fprint(w, `{"name": "repo", "parent": {"name": "repo", "full_name": "upstream/repo", "owner": {"login": "upstream"}}}`)
+				case strings.HasPrefix(r.URL.Path, "/api/v3/repos/me/repo/compare/"):
+					comparison, ok := tc.comparisons[strings.TrimPrefix(r.URL.Path, "/api/v3/repos/me/repo/compare/")]
+					if !ok {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					fmt.Fprint(w, comparison)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			fork := &github.Repository{Name: toPtr("repo"), Owner: &github.User{Login: toPtr("me")}, Fork: toPtr(true)}
+			status, err := getForkStatus(context.Background(), c, fork, tc.branch)
+			require.NoError(err)
+			assert.Equal(tc.want, *status)
+		})
+	}
+}
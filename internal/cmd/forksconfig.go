@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultForksConfigPath is the per-fork configuration read by sync-forks if it exists.
+const defaultForksConfigPath = ".ghh/forks.yaml"
+
+// Values of the merge setting of a fork.
+const (
+	forkMergeAllowed = "merge"
+	forkMergeFFOnly  = "ff-only"
+)
+
+// scheduleIntervals maps schedule hints to the time after which a fork is synced again.
+// A fork with the manual schedule is only synced with '--ignore-schedule'.
+var scheduleIntervals = map[string]time.Duration{
+	"always":  0,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"manual":  -1,
+}
+
+// forkConfig is the configuration of one fork in the forks file.
+type forkConfig struct {
+	// targetBranches overrides '--target-branches' for the fork, without falling back
+	// to the default branch.
+	targetBranches []string
+	// upstreamBranch is the branch of the parent the target branch is synced with.
+	upstreamBranch string
+	// ffOnly forbids merge commits, the target branch is only fast-forwarded.
+	ffOnly   bool
+	ignore   bool
+	syncTags bool
+	schedule string
+}
+
+// due reports whether the fork should be synced according to its schedule, given the
+// time of its last sync. A zero lastSync means the fork was never synced.
+func (c *forkConfig) due(lastSync, now time.Time) bool {
+	interval, ok := scheduleIntervals[c.schedule]
+	if !ok || interval == 0 {
+		return true
+	}
+	if interval < 0 {
+		return false
+	}
+	return !lastSync.Add(interval).After(now)
+}
+
+// forksConfig is the per-fork configuration of sync-forks, keyed by the lowercase
+// repository name or full name.
+type forksConfig struct {
+	forks map[string]*forkConfig
+}
+
+// lookup returns the configuration of the fork, or nil if there is none. An entry for
+// the full name owner/name takes precedence over an entry for the name.
+func (c *forksConfig) lookup(fork *github.Repository) *forkConfig {
+	if c == nil {
+		return nil
+	}
+	if cfg, ok := c.forks[strings.ToLower(fork.GetFullName())]; ok {
+		return cfg
+	}
+	return c.forks[strings.ToLower(fork.GetName())]
+}
+
+// loadForksConfig reads the forks file. A missing file is only an error if required is set.
+func loadForksConfig(path string, required bool) (*forksConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading forks file: %w", err)
+	}
+	return parseForksConfig(path, data)
+}
+
+// parseForksConfig parses and validates the forks file. All problems are reported
+// at once, with the line and column they were found at.
+//
+//	forks:
+//	  katexochen/ghh:
+//	    targetBranches: [develop]
+//	    upstreamBranch: main
+//	    merge: ff-only
+//	    syncTags: true
+//	    schedule: weekly
+func parseForksConfig(path string, data []byte) (*forksConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	p := &forksConfigParser{path: path}
+	config := &forksConfig{forks: map[string]*forkConfig{}}
+	if len(doc.Content) == 0 {
+		return config, nil
+	}
+
+	for _, top := range p.mapping(doc.Content[0]) {
+		if top.key.Value != "forks" {
+			p.errorf(top.key, "unknown key %q, expected forks", top.key.Value)
+			continue
+		}
+		for _, entry := range p.mapping(top.value) {
+			repo := strings.ToLower(entry.key.Value)
+			if !validForkKey(repo) {
+				p.errorf(entry.key, "invalid repository %q, expected NAME or OWNER/NAME", entry.key.Value)
+				continue
+			}
+			if _, ok := config.forks[repo]; ok {
+				p.errorf(entry.key, "duplicate entry for %s", entry.key.Value)
+				continue
+			}
+			config.forks[repo] = p.fork(entry.value)
+		}
+	}
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	return config, nil
+}
+
+// validForkKey reports whether the key of a forks file entry is a repository NAME or OWNER/NAME.
+func validForkKey(key string) bool {
+	owner, name, found := strings.Cut(key, "/")
+	if !found {
+		return owner != ""
+	}
+	return owner != "" && name != "" && !strings.Contains(name, "/")
+}
+
+// forksConfigParser collects the validation errors of a forks file.
+type forksConfigParser struct {
+	path string
+	errs []error
+}
+
+func (p *forksConfigParser) errorf(node *yaml.Node, format string, args ...any) {
+	p.errs = append(p.errs, fmt.Errorf("%s:%d:%d: %s", p.path, node.Line, node.Column, fmt.Sprintf(format, args...)))
+}
+
+type yamlEntry struct {
+	key   *yaml.Node
+	value *yaml.Node
+}
+
+// mapping returns the entries of a mapping node in order. Keys that aren't
+// scalars are reported.
+func (p *forksConfigParser) mapping(node *yaml.Node) []yamlEntry {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "expected a mapping")
+		return nil
+	}
+	var entries []yamlEntry
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Kind != yaml.ScalarNode {
+			p.errorf(key, "expected a string key")
+			continue
+		}
+		entries = append(entries, yamlEntry{key: key, value: node.Content[i+1]})
+	}
+	return entries
+}
+
+func (p *forksConfigParser) fork(node *yaml.Node) *forkConfig {
+	cfg := &forkConfig{}
+	seen := map[string]bool{}
+	for _, entry := range p.mapping(node) {
+		key, value := entry.key, entry.value
+		if seen[key.Value] {
+			p.errorf(key, "duplicate key %q", key.Value)
+			continue
+		}
+		seen[key.Value] = true
+
+		switch key.Value {
+		case "targetBranches":
+			cfg.targetBranches = p.strings(value)
+		case "upstreamBranch":
+			cfg.upstreamBranch = p.string(value)
+		case "merge":
+			switch merge := p.string(value); merge {
+			case forkMergeAllowed:
+			case forkMergeFFOnly:
+				cfg.ffOnly = true
+			default:
+				p.errorf(value, "invalid merge %q, expected %s or %s", merge, forkMergeAllowed, forkMergeFFOnly)
+			}
+		case "ignore":
+			cfg.ignore = p.bool(value)
+		case "syncTags":
+			cfg.syncTags = p.bool(value)
+		case "schedule":
+			cfg.schedule = p.string(value)
+			if _, ok := scheduleIntervals[cfg.schedule]; !ok {
+				p.errorf(value, "invalid schedule %q, expected always, daily, weekly, monthly or manual", cfg.schedule)
+			}
+		default:
+			p.errorf(key, "unknown field %q, expected one of targetBranches, upstreamBranch, merge, ignore, syncTags, schedule", key.Value)
+		}
+	}
+	return cfg
+}
+
+func (p *forksConfigParser) string(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode || node.Tag == "!!null" || node.Value == "" {
+		p.errorf(node, "expected a non-empty string")
+		return ""
+	}
+	return node.Value
+}
+
+func (p *forksConfigParser) strings(node *yaml.Node) []string {
+	if node.Kind == yaml.ScalarNode {
+		if s := p.string(node); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		p.errorf(node, "expected a non-empty list of strings")
+		return nil
+	}
+	var list []string
+	for _, item := range node.Content {
+		if s := p.string(item); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func (p *forksConfigParser) bool(node *yaml.Node) bool {
+	var b bool
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" || node.Decode(&b) != nil {
+		p.errorf(node, "expected true or false")
+	}
+	return b
+}
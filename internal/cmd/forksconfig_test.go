@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForksConfig(t *testing.T) {
+	testCases := []struct {
+		config   string
+		want     map[string]*forkConfig
+		wantErrs []string
+	}{
+		{ // empty
+			config: "",
+			want:   map[string]*forkConfig{},
+		},
+		{ // all fields
+			config: `
+forks:
+  katexochen/GHH:
+    targetBranches: [develop, main]
+    upstreamBranch: main
+    merge: ff-only
+    syncTags: true
+    schedule: weekly
+  dotfiles:
+    ignore: true
+  tools:
+    targetBranches: sync
+    merge: merge
+`,
+			want: map[string]*forkConfig{
+				"katexochen/ghh": {
+					targetBranches: []string{"develop", "main"},
+					upstreamBranch: "main",
+					ffOnly:         true,
+					syncTags:       true,
+					schedule:       "weekly",
+				},
+				"dotfiles": {ignore: true},
+				"tools":    {targetBranches: []string{"sync"}},
+			},
+		},
+		{ // invalid values
+			config: `
+forks:
+  ghh:
+    targetBranch: develop
+    merge: rebase
+    syncTags: "yes"
+    schedule: hourly
+  a/b/c: {}
+  ghh: {}
+`,
+			wantErrs: []string{
+				`forks.yaml:4:5: unknown field "targetBranch"`,
+				`forks.yaml:5:12: invalid merge "rebase"`,
+				`forks.yaml:6:15: expected true or false`,
+				`forks.yaml:7:15: invalid schedule "hourly"`,
+				`forks.yaml:8:3: invalid repository "a/b/c"`,
+				`forks.yaml:9:3: duplicate entry for ghh`,
+			},
+		},
+		{ // wrong structure
+			config: `
+repos:
+  - ghh
+forks:
+  - ghh
+`,
+			wantErrs: []string{
+				`forks.yaml:2:1: unknown key "repos"`,
+				`forks.yaml:5:3: expected a mapping`,
+			},
+		},
+		{ // empty target branches
+			config: `
+forks:
+  ghh:
+    targetBranches: []
+    upstreamBranch: ""
+`,
+			wantErrs: []string{
+				`forks.yaml:4:21: expected a non-empty list of strings`,
+				`forks.yaml:5:21: expected a non-empty string`,
+			},
+		},
+		{ // invalid yaml
+			config:   "forks:\n  ghh: [\n",
+			wantErrs: []string{"parsing forks.yaml"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			config, err := parseForksConfig("forks.yaml", []byte(tc.config))
+			if len(tc.wantErrs) > 0 {
+				require.Error(t, err)
+				for _, wantErr := range tc.wantErrs {
+					assert.Contains(err.Error(), wantErr)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, config.forks)
+		})
+	}
+}
+
+func TestForksConfigLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := parseForksConfig("forks.yaml", []byte(`
+forks:
+  ghh:
+    schedule: daily
+  katexochen/ghh:
+    schedule: weekly
+`))
+	require.NoError(t, err)
+
+	fork := func(fullName, name string) *github.Repository {
+		return &github.Repository{FullName: toPtr(fullName), Name: toPtr(name)}
+	}
+	assert.Equal("weekly", config.lookup(fork("Katexochen/ghh", "ghh")).schedule)
+	assert.Equal("daily", config.lookup(fork("someone/ghh", "ghh")).schedule)
+	assert.Nil(config.lookup(fork("katexochen/other", "other")))
+
+	var noConfig *forksConfig
+	assert.Nil(noConfig.lookup(fork("katexochen/ghh", "ghh")))
+}
+
+func TestForkConfigDue(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		schedule string
+		lastSync time.Time
+		want     bool
+	}{
+		{lastSync: now, want: true},
+		{schedule: "always", lastSync: now, want: true},
+		{schedule: "daily", lastSync: now.Add(-time.Hour), want: false},
+		{schedule: "daily", lastSync: now.Add(-25 * time.Hour), want: true},
+		{schedule: "weekly", lastSync: now.Add(-3 * 24 * time.Hour), want: false},
+		// A fork that was never synced is due.
+		{schedule: "monthly", want: true},
+		{schedule: "manual", want: false},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cfg := &forkConfig{schedule: tc.schedule}
+			assert.Equal(t, tc.want, cfg.due(tc.lastSync, now))
+		})
+	}
+}
+
+func TestFilterConfiguredForks(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	config, err := parseForksConfig("forks.yaml", []byte(`
+forks:
+  ignored:
+    ignore: true
+  recent:
+    schedule: weekly
+  manual:
+    schedule: manual
+`))
+	require.NoError(t, err)
+
+	var forks []*github.Repository
+	for _, name := range []string{"ignored", "recent", "manual", "other"} {
+		forks = append(forks, &github.Repository{
+			Name:     toPtr(name),
+			FullName: toPtr("me/" + name),
+			PushedAt: &github.Timestamp{Time: now.Add(-time.Hour)},
+		})
+	}
+
+	names := func(repos []*github.Repository) []string {
+		var names []string
+		for _, repo := range repos {
+			names = append(names, repo.GetName())
+		}
+		return names
+	}
+	assert.Equal([]string{"other"}, names(filterConfiguredForks(forks, config, nil, false, now, newLogger(false))))
+	assert.Equal([]string{"recent", "manual", "other"}, names(filterConfiguredForks(forks, config, nil, true, now, newLogger(false))))
+	assert.Len(filterConfiguredForks(forks, nil, nil, false, now, newLogger(false)), 4)
+
+	// The recorded sync takes precedence over the last push.
+	state := &syncState{synced: map[string]time.Time{"me/recent": now.Add(-8 * 24 * time.Hour)}}
+	assert.Equal([]string{"recent", "other"}, names(filterConfiguredForks(forks, config, state, false, now, newLogger(false))))
+}
@@ -1,23 +1,228 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
+
+	"github.com/spf13/cobra"
 )
 
-func findOwnerAndRepo() (string, string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	remoteURL, err := cmd.Output()
+// repoEnvVar selects the repository like the gh CLI, as [HOST/]OWNER/REPO.
+const repoEnvVar = "GH_REPO"
+
+// repoRef identifies a repository by owner and name. The host is empty
+// if the repository was given without one.
+type repoRef struct {
+	host  string
+	owner string
+	name  string
+}
+
+func (r repoRef) String() string {
+	return r.owner + "/" + r.name
+}
+
+// parseRepoRef parses a repository given as [HOST/]OWNER/REPO.
+func parseRepoRef(s string) (repoRef, error) {
+	parts := strings.Split(s, "/")
+	for _, part := range parts {
+		if part == "" {
+			return repoRef{}, fmt.Errorf("invalid repository %q, expected [HOST/]OWNER/REPO", s)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return repoRef{owner: parts[0], name: parts[1]}, nil
+	case 3:
+		return repoRef{host: normalizeHost(parts[0]), owner: parts[1], name: parts[2]}, nil
+	default:
+		return repoRef{}, fmt.Errorf("invalid repository %q, expected [HOST/]OWNER/REPO", s)
+	}
+}
+
+// commonHost returns the host of the repositories, which is empty if none of them
+// was given with a host. Repositories on different hosts can't be used together.
+func commonHost(repos []repoRef) (string, error) {
+	var host string
+	for _, r := range repos {
+		if r.host == "" {
+			continue
+		}
+		if host != "" && r.host != host {
+			return "", fmt.Errorf("repositories on different hosts (%s, %s) can't be used together", host, r.host)
+		}
+		host = r.host
+	}
+	return host, nil
+}
+
+// findRepo returns the repository a command operates on. It is taken from the
+// repo flag, the GH_REPO environment variable, or the git remotes of the current
+// directory, in that order. The remotes are tried in the order of --remote.
+func findRepo(cmd *cobra.Command, repoFlag string) (repoRef, error) {
+	if repoFlag != "" {
+		return parseRepoRef(repoFlag)
+	}
+	if repo := os.Getenv(repoEnvVar); repo != "" {
+		ref, err := parseRepoRef(repo)
+		if err != nil {
+			return repoRef{}, fmt.Errorf("parsing %s: %w", repoEnvVar, err)
+		}
+		return ref, nil
+	}
+
+	preferred, err := cmd.Flags().GetStringSlice("remote")
+	if err != nil {
+		return repoRef{}, err
+	}
+	remotes, err := gitConfigEntries(`^remote\..*\.url$`)
+	if err != nil {
+		return repoRef{}, fmt.Errorf("reading git remotes: %w", err)
+	}
+	rewrites, err := gitConfigEntries(`^url\..*\.insteadof$`)
 	if err != nil {
-		return "", "", err
+		return repoRef{}, fmt.Errorf("reading git URL rewrites: %w", err)
+	}
+	return repoFromRemotes(remotes, rewrites, preferred)
+}
+
+// gitConfigEntry is a key and value of the git configuration.
+type gitConfigEntry struct {
+	key   string
+	value string
+}
+
+// gitConfigEntries returns the git configuration entries with a key matching the regexp.
+func gitConfigEntries(keyRegexp string) ([]gitConfigEntry, error) {
+	out, err := exec.Command("git", "config", "--get-regexp", keyRegexp).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// No matching key.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
-	ownerRepo := strings.TrimSpace(string(remoteURL))
-	ownerRepo = strings.TrimPrefix(ownerRepo, "https://github.com/")
-	ownerRepo = strings.TrimSuffix(ownerRepo, ".git")
-	if strings.Count(ownerRepo, "/") != 1 {
-		return "", "", fmt.Errorf("invalid remote URL: %s", ownerRepo)
+	return parseGitConfigEntries(string(out)), nil
+}
+
+func parseGitConfigEntries(out string) []gitConfigEntry {
+	var entries []gitConfigEntry
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		entries = append(entries, gitConfigEntry{key: key, value: value})
+	}
+	return entries
+}
+
+// repoFromRemotes returns the repository of the first preferred remote, or of the
+// first other remote pointing to a repository if none of the preferred ones exist.
+// The remote URLs are rewritten by the url.<base>.insteadOf rules before parsing.
+func repoFromRemotes(remoteEntries, rewriteEntries []gitConfigEntry, preferred []string) (repoRef, error) {
+	remotes := map[string]string{}
+	var names []string
+	for _, e := range remoteEntries {
+		name := strings.TrimSuffix(strings.TrimPrefix(e.key, "remote."), ".url")
+		// Like git, the first URL of a remote is used for fetching.
+		if _, ok := remotes[name]; !ok {
+			names = append(names, name)
+			remotes[name] = e.value
+		}
+	}
+	if len(remotes) == 0 {
+		return repoRef{}, errors.New("no git remotes found, run the command in a git repository or pass the repository")
+	}
+
+	rewrites := map[string]string{}
+	for _, e := range rewriteEntries {
+		base := strings.TrimSuffix(strings.TrimPrefix(e.key, "url."), ".insteadof")
+		rewrites[e.value] = base
+	}
+
+	for _, name := range preferred {
+		remoteURL, ok := remotes[name]
+		if !ok {
+			continue
+		}
+		repo, err := parseRemoteURL(rewriteURL(remoteURL, rewrites))
+		if err != nil {
+			return repoRef{}, fmt.Errorf("remote %s: %w", name, err)
+		}
+		return repo, nil
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if repo, err := parseRemoteURL(rewriteURL(remotes[name], rewrites)); err == nil {
+			return repo, nil
+		}
+	}
+	return repoRef{}, fmt.Errorf("none of the git remotes %s points to a repository", strings.Join(names, ", "))
+}
+
+// rewriteURL applies the insteadOf rule with the longest matching prefix, like git.
+func rewriteURL(remoteURL string, rewrites map[string]string) string {
+	var longest string
+	for prefix := range rewrites {
+		if strings.HasPrefix(remoteURL, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return remoteURL
+	}
+	return rewrites[longest] + strings.TrimPrefix(remoteURL, longest)
+}
+
+// parseRemoteURL parses the host, owner and name of a repository from a git remote URL.
+// URLs with a scheme, like https:// and ssh://, and scp-like URLs, like
+// git@github.com:owner/repo.git, are supported.
+func parseRemoteURL(remoteURL string) (repoRef, error) {
+	var host, repoPath string
+	if scheme, _, ok := strings.Cut(remoteURL, "://"); ok && scheme != "" {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return repoRef{}, fmt.Errorf("invalid remote URL %q: %w", remoteURL, err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git", "git+ssh", "ssh+git":
+		default:
+			return repoRef{}, fmt.Errorf("unsupported remote URL scheme %q", u.Scheme)
+		}
+		host, repoPath = u.Hostname(), u.Path
+	} else {
+		// scp-like syntax [user@]host:path. A colon after a slash is part of a local path.
+		hostPart, pathPart, ok := strings.Cut(remoteURL, ":")
+		if !ok || strings.Contains(hostPart, "/") {
+			return repoRef{}, fmt.Errorf("invalid remote URL %q", remoteURL)
+		}
+		if _, h, ok := strings.Cut(hostPart, "@"); ok {
+			hostPart = h
+		}
+		host, repoPath = hostPart, pathPart
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	owner, name, ok := strings.Cut(repoPath, "/")
+	if host == "" || !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return repoRef{}, fmt.Errorf("remote URL %q doesn't point to a repository", remoteURL)
+	}
+	return repoRef{host: normalizeHost(host), owner: owner, name: name}, nil
+}
+
+// normalizeHost maps alternative hosts of github.com to the API host.
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	switch host {
+	case "ssh.github.com", "www.github.com":
+		return defaultHost
+	default:
+		return host
 	}
-	owner, repo, _ := strings.Cut(ownerRepo, "/")
-	return owner, repo, nil
 }
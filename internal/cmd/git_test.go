@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRepoRef(t *testing.T) {
+	testCases := []struct {
+		repo    string
+		want    repoRef
+		wantErr bool
+	}{
+		{repo: "katexochen/ghh", want: repoRef{owner: "katexochen", name: "ghh"}}, // owner and name
+		{repo: "ghh", wantErr: true},         // no owner
+		{repo: "/ghh", wantErr: true},        // empty owner
+		{repo: "katexochen/", wantErr: true}, // empty name
+		{ // with host
+			repo: "GHE.example.com/katexochen/ghh",
+			want: repoRef{host: "ghe.example.com", owner: "katexochen", name: "ghh"},
+		},
+		{repo: "/katexochen/ghh", wantErr: true},                 // empty host
+		{repo: "github.com/katexochen/ghh/pulls", wantErr: true}, // too many parts
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			repo, err := parseRepoRef(tc.repo)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, repo)
+		})
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	testCases := []struct {
+		url     string
+		want    repoRef
+		wantErr bool
+	}{
+		{ // https
+			url:  "https://github.com/katexochen/ghh.git",
+			want: repoRef{host: "github.com", owner: "katexochen", name: "ghh"},
+		},
+		{ // https without suffix
+			url:  "https://github.com/katexochen/ghh",
+			want: repoRef{host: "github.com", owner: "katexochen", name: "ghh"},
+		},
+		{ // https with user and trailing slash
+			url:  "https://token@github.com/katexochen/ghh/",
+			want: repoRef{host: "github.com", owner: "katexochen", name: "ghh"},
+		},
+		{ // scp-like
+			url:  "git@github.com:katexochen/ghh.git",
+			want: repoRef{host: "github.com", owner: "katexochen", name: "ghh"},
+		},
+		{ // scp-like without user
+			url:  "ghe.example.com:team/service",
+			want: repoRef{host: "ghe.example.com", owner: "team", name: "service"},
+		},
+		{ // ssh with port
+			url:  "ssh://git@ssh.github.com:443/katexochen/ghh.git",
+			want: repoRef{host: "github.com", owner: "katexochen", name: "ghh"},
+		},
+		{ // enterprise
+			url:  "https://GHE.example.com/team/service.git",
+			want: repoRef{host: "ghe.example.com", owner: "team", name: "service"},
+		},
+		{ // git protocol
+			url:  "git://github.com/katexochen/ghh.git",
+			want: repoRef{host: "github.com", owner: "katexochen", name: "ghh"},
+		},
+		{url: "/srv/git/ghh.git", wantErr: true},                             // local path
+		{url: "../ghh", wantErr: true},                                       // relative path
+		{url: "file:///srv/git/katexochen/ghh.git", wantErr: true},           // file scheme
+		{url: "https://github.com/ghh.git", wantErr: true},                   // missing owner
+		{url: "https://gitlab.example.com/group/sub/ghh.git", wantErr: true}, // too deep
+		{url: "git@github.com:", wantErr: true},                              // scp-like no path
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			repo, err := parseRemoteURL(tc.url)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, repo)
+		})
+	}
+}
+
+func TestRepoFromRemotes(t *testing.T) {
+	ghh := repoRef{host: "github.com", owner: "katexochen", name: "ghh"}
+	fork := repoRef{host: "github.com", owner: "someone", name: "ghh"}
+
+	testCases := []struct {
+		config    string
+		preferred []string
+		want      repoRef
+		wantErr   bool
+	}{
+		{ // origin
+			config:    "remote.origin.url https://github.com/katexochen/ghh.git\n",
+			preferred: []string{"origin", "upstream"},
+			want:      ghh,
+		},
+		{ // origin preferred
+			config: "remote.origin.url git@github.com:someone/ghh.git\n" +
+				"remote.upstream.url https://github.com/katexochen/ghh.git\n",
+			preferred: []string{"origin", "upstream"},
+			want:      fork,
+		},
+		{ // upstream preferred by configuration
+			config: "remote.origin.url git@github.com:someone/ghh.git\n" +
+				"remote.upstream.url https://github.com/katexochen/ghh.git\n",
+			preferred: []string{"upstream", "origin"},
+			want:      ghh,
+		},
+		{ // first URL of a remote
+			config: "remote.origin.url https://github.com/katexochen/ghh.git\n" +
+				"remote.origin.url git@github.com:someone/ghh.git\n",
+			preferred: []string{"origin"},
+			want:      ghh,
+		},
+		{ // fallback to other remote
+			config: "remote.backup.url /mnt/backup/ghh.git\n" +
+				"remote.my.fork.url git@github.com:someone/ghh.git\n",
+			preferred: []string{"origin", "upstream"},
+			want:      fork,
+		},
+		{ // insteadOf rewrite
+			config: "remote.origin.url gh:katexochen/ghh\n" +
+				"url.git@github.com:.insteadof gh:\n",
+			preferred: []string{"origin"},
+			want:      ghh,
+		},
+		{ // longest insteadOf wins
+			config: "remote.origin.url work:team/service\n" +
+				"url.https://github.com/.insteadof w\n" +
+				"url.https://ghe.example.com/.insteadof work:\n",
+			preferred: []string{"origin"},
+			want:      repoRef{host: "ghe.example.com", owner: "team", name: "service"},
+		},
+		{ // invalid preferred remote
+			config:    "remote.origin.url /srv/git/ghh.git\n",
+			preferred: []string{"origin"},
+			wantErr:   true,
+		},
+		{ // no remotes
+			preferred: []string{"origin"},
+			wantErr:   true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			var remotes, rewrites []gitConfigEntry
+			for _, e := range parseGitConfigEntries(tc.config) {
+				if strings.HasPrefix(e.key, "url.") {
+					rewrites = append(rewrites, e)
+				} else {
+					remotes = append(remotes, e)
+				}
+			}
+
+			repo, err := repoFromRemotes(remotes, rewrites, tc.preferred)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, repo)
+		})
+	}
+}
+
+func TestCommonHost(t *testing.T) {
+	assert := assert.New(t)
+
+	host, err := commonHost([]repoRef{{owner: "a", name: "b"}, {host: "ghe.example.com", owner: "c", name: "d"}})
+	assert.NoError(err)
+	assert.Equal("ghe.example.com", host)
+
+	_, err = commonHost([]repoRef{{host: "github.com", owner: "a", name: "b"}, {host: "ghe.example.com", owner: "c", name: "d"}})
+	assert.Error(err)
+}
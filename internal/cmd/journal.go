@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// journal records the IDs of processed items in a file, so an interrupted
+// operation can be resumed without processing them again.
+type journal struct {
+	path string
+	file *os.File
+
+	mux  sync.Mutex
+	done map[int64]bool
+}
+
+// openJournal opens the journal at path, loading the IDs recorded by earlier runs.
+func openJournal(path string) (*journal, error) {
+	j := &journal{path: path, done: map[int64]bool{}}
+
+	existing, err := os.Open(path)
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				// A truncated last line is expected if the program was killed while writing.
+				continue
+			}
+			j.done[id] = true
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading journal %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	j.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Len returns the number of recorded IDs.
+func (j *journal) Len() int {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	return len(j.done)
+}
+
+// Contains reports whether the ID was recorded.
+func (j *journal) Contains(id int64) bool {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	return j.done[id]
+}
+
+// Record adds the ID to the journal.
+func (j *journal) Record(id int64) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	j.done[id] = true
+	_, err := fmt.Fprintln(j.file, id)
+	return err
+}
+
+// Close closes the journal file, keeping it for a later resume.
+func (j *journal) Close() error {
+	return j.file.Close()
+}
+
+// Remove closes and deletes the journal file, once the operation completed.
+func (j *journal) Remove() error {
+	return errors.Join(j.file.Close(), os.Remove(j.path))
+}
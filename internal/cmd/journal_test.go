@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalResume(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "journal", "runs")
+
+	j, err := openJournal(path)
+	require.NoError(err)
+	assert.Equal(0, j.Len())
+	require.NoError(j.Record(1))
+	require.NoError(j.Record(2))
+	require.NoError(j.Close())
+
+	// Simulate a write interrupted by a kill.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(err)
+	_, err = f.WriteString("3x")
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	j, err = openJournal(path)
+	require.NoError(err)
+	assert.Equal(2, j.Len())
+	assert.True(j.Contains(1))
+	assert.True(j.Contains(2))
+	assert.False(j.Contains(3))
+
+	require.NoError(j.Remove())
+	_, err = os.Stat(path)
+	assert.ErrorIs(err, os.ErrNotExist)
+}
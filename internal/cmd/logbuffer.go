@@ -0,0 +1,76 @@
+package cmd
+
+import "sync"
+
+// logGroups hands out loggers that buffer the lines of one task, so the lines of
+// tasks running concurrently stay grouped in the output.
+type logGroups struct {
+	parent loggerI
+	mux    sync.Mutex
+}
+
+func newLogGroups(parent loggerI) *logGroups {
+	return &logGroups{parent: parent}
+}
+
+// group returns a logger for one task. It must only be used by a single goroutine.
+func (g *logGroups) group() *bufferedLogger {
+	return &bufferedLogger{groups: g}
+}
+
+// bufferedLogger collects log lines until Flush writes them to the parent logger.
+type bufferedLogger struct {
+	groups *logGroups
+	lines  []func(loggerI)
+}
+
+func (l *bufferedLogger) add(line func(loggerI)) {
+	l.lines = append(l.lines, line)
+}
+
+// Flush writes the collected lines to the parent logger without interleaving them
+// with the lines of other groups.
+func (l *bufferedLogger) Flush() {
+	l.groups.mux.Lock()
+	defer l.groups.mux.Unlock()
+	for _, line := range l.lines {
+		line(l.groups.parent)
+	}
+	l.lines = nil
+}
+
+func (l *bufferedLogger) Infof(format string, args ...any) {
+	l.add(func(p loggerI) { p.Infof(format, args...) })
+}
+
+func (l *bufferedLogger) Infoln(args ...any) {
+	l.add(func(p loggerI) { p.Infoln(args...) })
+}
+
+func (l *bufferedLogger) Warnf(format string, args ...any) {
+	l.add(func(p loggerI) { p.Warnf(format, args...) })
+}
+
+func (l *bufferedLogger) Warnln(args ...any) {
+	l.add(func(p loggerI) { p.Warnln(args...) })
+}
+
+func (l *bufferedLogger) Errorf(format string, args ...any) {
+	l.add(func(p loggerI) { p.Errorf(format, args...) })
+}
+
+func (l *bufferedLogger) Errorln(args ...any) {
+	l.add(func(p loggerI) { p.Errorln(args...) })
+}
+
+func (l *bufferedLogger) Debugf(format string, args ...any) {
+	l.add(func(p loggerI) { p.Debugf(format, args...) })
+}
+
+func (l *bufferedLogger) Debugln(args ...any) {
+	l.add(func(p loggerI) { p.Debugln(args...) })
+}
+
+func (l *bufferedLogger) PrintJSON(msg string, v any) {
+	l.add(func(p loggerI) { p.PrintJSON(msg, v) })
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingLogger records the info lines it receives.
+type recordingLogger struct {
+	loggerI
+	lines []string
+}
+
+func (l *recordingLogger) Infof(format string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLogGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	parent := &recordingLogger{}
+	groups := newLogGroups(parent)
+
+	var wg sync.WaitGroup
+	for task := 0; task < 10; task++ {
+		wg.Add(1)
+		go func(task int) {
+			defer wg.Done()
+			log := groups.group()
+			defer log.Flush()
+			for line := 0; line < 5; line++ {
+				log.Infof("task %d line %d", task, line)
+			}
+		}(task)
+	}
+	wg.Wait()
+
+	assert.Len(parent.lines, 50)
+	for i := 0; i < len(parent.lines); i += 5 {
+		var task int
+		_, err := fmt.Sscanf(parent.lines[i], "task %d line 0", &task)
+		assert.NoError(err)
+		for line := 0; line < 5; line++ {
+			assert.Equal(fmt.Sprintf("task %d line %d", task, line), parent.lines[i+line])
+		}
+	}
+}
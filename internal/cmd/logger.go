@@ -1,5 +1,7 @@
 package cmd
 
+import "github.com/katexochen/ghh/internal/logger"
+
 type loggerI interface {
 	Infof(format string, args ...any)
 	Infoln(args ...any)
@@ -11,3 +13,10 @@ type loggerI interface {
 	Debugln(args ...any)
 	PrintJSON(msg string, v any)
 }
+
+func newLogger(verbose bool) loggerI {
+	if verbose {
+		return &logger.VerboseLogger{}
+	}
+	return &logger.DefaultLogger{}
+}
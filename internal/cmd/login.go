@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	oauthClientIDEnvVar = "GHH_OAUTH_CLIENT_ID"
+	defaultLoginScopes  = "repo,workflow,project"
+
+	// slowDownIncrease is added to the polling interval on a slow_down error.
+	slowDownIncrease = 5 * time.Second
+)
+
+// NewLoginCmd creates a new command for logging in with the OAuth device flow.
+func NewLoginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to GitHub with the OAuth device flow",
+		Long: `
+Log in to GitHub with the OAuth device authorization flow of an OAuth app.
+
+The command prints a code, which must be entered on the GitHub device page. Once
+authorized, the token is stored like with 'set-auth'.
+		`,
+		RunE: login,
+	}
+	cmd.Flags().String(
+		"client-id",
+		"",
+		"Client ID of the OAuth app to authorize (env GHH_OAUTH_CLIENT_ID)",
+	)
+	cmd.Flags().StringSlice(
+		"scopes",
+		strings.Split(defaultLoginScopes, ","),
+		"OAuth scopes to request",
+	)
+	cmd.Flags().String(
+		"store",
+		storeAuto,
+		fmt.Sprintf("Credential store to use, one of %q, %q, %q", storeAuto, storeSecretService, storeEncryptedFile),
+	)
+	cmd.Flags().Bool(
+		"insecure-storage",
+		false,
+		"Store the token unencrypted in the settings file",
+	)
+	cmd.Flags().String(
+		"device-code-url",
+		"",
+		"URL of the device code endpoint. Derived from the host if empty.",
+	)
+	cmd.Flags().String(
+		"token-url",
+		"",
+		"URL of the access token endpoint. Derived from the host if empty.",
+	)
+	return cmd
+}
+
+func login(cmd *cobra.Command, _ []string) error {
+	flags, err := parseLoginFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	p, err := lookupProfile(cmd, true, "")
+	if err != nil {
+		return err
+	}
+
+	store, err := selectCredentialStore(flags.store, flags.insecureStorage)
+	if err != nil {
+		return err
+	}
+
+	flow := &deviceFlow{
+		clientID:      flags.clientID,
+		scopes:        flags.scopes,
+		deviceCodeURL: flags.deviceCodeURL,
+		tokenURL:      flags.tokenURL,
+		httpClient:    http.DefaultClient,
+		sleep:         sleepContext,
+	}
+	if flow.deviceCodeURL == "" {
+		flow.deviceCodeURL = fmt.Sprintf("https://%s/login/device/code", p.Host)
+	}
+	if flow.tokenURL == "" {
+		flow.tokenURL = fmt.Sprintf("https://%s/login/oauth/access_token", p.Host)
+	}
+
+	code, err := flow.requestCode(cmd.Context())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("First copy your one-time code: %s\n", code.UserCode)
+	fmt.Printf("Then open %s in your browser and enter the code.\n", code.VerificationURI)
+	fmt.Println("Waiting for authorization...")
+
+	token, err := flow.pollToken(cmd.Context(), code)
+	if err != nil {
+		return err
+	}
+
+	info, err := inspectToken(cmd.Context(), p, token, newLogger(false))
+	if err != nil {
+		return fmt.Errorf("validating token: %w", err)
+	}
+
+	if err := storeToken(p, store, token); err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged in as %s, saved token for profile %q (%s) to %s store.\n", info.login, p.Name(), p.Host, store.Name())
+	return nil
+}
+
+// deviceFlow implements the OAuth 2.0 device authorization grant as provided by GitHub, see
+// https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps#device-flow.
+type deviceFlow struct {
+	clientID      string
+	scopes        []string
+	deviceCodeURL string
+	tokenURL      string
+	httpClient    *http.Client
+	// sleep waits for the given duration or until the context is done.
+	sleep func(context.Context, time.Duration) error
+}
+
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+func (f *deviceFlow) requestCode(ctx context.Context) (*deviceCode, error) {
+	form := url.Values{
+		"client_id": {f.clientID},
+		"scope":     {strings.Join(f.scopes, " ")},
+	}
+	var code deviceCode
+	if err := f.post(ctx, f.deviceCodeURL, form, &code); err != nil {
+		return nil, fmt.Errorf("requesting device code: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, errors.New("requesting device code: response is missing the device or user code")
+	}
+	return &code, nil
+}
+
+// errDeviceCodeExpired is returned if the user didn't authorize the device in time.
+var errDeviceCodeExpired = errors.New("the device code expired, please run login again")
+
+// pollToken polls the token endpoint until the user authorized the device, the code
+// expired, or the user denied access. The interval is increased on slow_down. Polling
+// stops once the code expired, even if the server doesn't answer.
+func (f *deviceFlow) pollToken(ctx context.Context, code *deviceCode) (string, error) {
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	form := url.Values{
+		"client_id":   {f.clientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	for {
+		if err := f.sleep(ctx, interval); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return "", errDeviceCodeExpired
+			}
+			return "", err
+		}
+
+		var resp deviceTokenResponse
+		if err := f.post(ctx, f.tokenURL, form, &resp); err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", errDeviceCodeExpired
+			}
+			return "", fmt.Errorf("polling access token: %w", err)
+		}
+
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return "", errors.New("polling access token: response is missing the access token")
+			}
+			return resp.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			if resp.Interval > 0 {
+				interval = time.Duration(resp.Interval) * time.Second
+			} else {
+				interval += slowDownIncrease
+			}
+		case "expired_token":
+			return "", errDeviceCodeExpired
+		case "access_denied":
+			return "", errors.New("authorization was denied")
+		default:
+			return "", fmt.Errorf("polling access token: %s: %s", resp.Error, resp.ErrorDescription)
+		}
+	}
+}
+
+func (f *deviceFlow) post(ctx context.Context, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type loginFlags struct {
+	clientID        string
+	scopes          []string
+	store           string
+	insecureStorage bool
+	deviceCodeURL   string
+	tokenURL        string
+}
+
+func parseLoginFlags(cmd *cobra.Command) (*loginFlags, error) {
+	flags := &loginFlags{}
+
+	var err error
+	flags.clientID, err = cmd.Flags().GetString("client-id")
+	if err != nil {
+		return nil, err
+	}
+	if flags.clientID == "" {
+		flags.clientID = os.Getenv(oauthClientIDEnvVar)
+	}
+	if flags.clientID == "" {
+		return nil, fmt.Errorf("'--client-id' or %s must be set", oauthClientIDEnvVar)
+	}
+	flags.scopes, err = cmd.Flags().GetStringSlice("scopes")
+	if err != nil {
+		return nil, err
+	}
+	flags.store, err = cmd.Flags().GetString("store")
+	if err != nil {
+		return nil, err
+	}
+	flags.insecureStorage, err = cmd.Flags().GetBool("insecure-storage")
+	if err != nil {
+		return nil, err
+	}
+	flags.deviceCodeURL, err = cmd.Flags().GetString("device-code-url")
+	if err != nil {
+		return nil, err
+	}
+	flags.tokenURL, err = cmd.Flags().GetString("token-url")
+	if err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
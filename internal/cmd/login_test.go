@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceFlow(t *testing.T) {
+	testCases := []struct {
+		tokenResponses []string
+		// expiresIn is the lifetime of the device code in seconds, 900 if unset.
+		expiresIn int
+		// silent makes the token endpoint never answer.
+		silent            bool
+		expectedToken     string
+		expectedIntervals []time.Duration
+		wantErr           bool
+		// wantExpired expects the error that the device code expired.
+		wantExpired bool
+	}{
+		{ // pending then token
+			tokenResponses: []string{
+				`{"error": "authorization_pending"}`,
+				`{"access_token": "gho_token"}`,
+			},
+			expectedToken:     "gho_token",
+			expectedIntervals: []time.Duration{time.Second, time.Second},
+		},
+		{ // slow down
+			tokenResponses: []string{
+				`{"error": "slow_down"}`,
+				`{"error": "slow_down", "interval": 10}`,
+				`{"access_token": "gho_token"}`,
+			},
+			expectedToken:     "gho_token",
+			expectedIntervals: []time.Duration{time.Second, 6 * time.Second, 10 * time.Second},
+		},
+		{ // expired
+			tokenResponses: []string{
+				`{"error": "authorization_pending"}`,
+				`{"error": "expired_token"}`,
+			},
+			expectedIntervals: []time.Duration{time.Second, time.Second},
+			wantExpired:       true,
+		},
+		{ // denied
+			tokenResponses:    []string{`{"error": "access_denied"}`},
+			expectedIntervals: []time.Duration{time.Second},
+			wantErr:           true,
+		},
+		{ // server doesn't answer until the code expired
+			expiresIn:         1,
+			silent:            true,
+			expectedIntervals: []time.Duration{time.Second},
+			wantExpired:       true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			var polls int
+			mux := http.NewServeMux()
+			mux.HandleFunc("/login/device/code", func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(r.ParseForm())
+				assert.Equal("client", r.PostForm.Get("client_id"))
+				assert.Equal("repo project", r.PostForm.Get("scope"))
+				expiresIn := tc.expiresIn
+				if expiresIn == 0 {
+					expiresIn = 900
+				}
+				fmt.Fprintf(w, `{"device_code": "dc", "user_code": "ABCD-1234", "verification_uri": "https://example.com/login/device",
+					"interval": 1, "expires_in": %d}`, expiresIn)
+			})
+			mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(r.ParseForm())
+				assert.Equal("dc", r.PostForm.Get("device_code"))
+				if tc.silent {
+					<-r.Context().Done()
+					return
+				}
+				fmt.Fprint(w, tc.tokenResponses[polls])
+				polls++
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			var intervals []time.Duration
+			flow := &deviceFlow{
+				clientID:      "client",
+				scopes:        []string{"repo", "project"},
+				deviceCodeURL: server.URL + "/login/device/code",
+				tokenURL:      server.URL + "/login/oauth/access_token",
+				httpClient:    server.Client(),
+				sleep: func(_ context.Context, d time.Duration) error {
+					intervals = append(intervals, d)
+					return nil
+				},
+			}
+
+			code, err := flow.requestCode(context.Background())
+			require.NoError(err)
+			assert.Equal("ABCD-1234", code.UserCode)
+
+			token, err := flow.pollToken(context.Background(), code)
+			switch {
+			case tc.wantExpired:
+				assert.ErrorIs(err, errDeviceCodeExpired)
+			case tc.wantErr:
+				assert.Error(err)
+			default:
+				require.NoError(err)
+				assert.Equal(tc.expectedToken, token)
+			}
+			assert.Equal(tc.expectedIntervals, intervals)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/google/go-github/v61/github"
+)
+
+const (
+	// perPage is the maximum page size of the GitHub API.
+	perPage = 100
+	// listConcurrency is the number of pages fetched in parallel by the client.
+	listConcurrency = 4
+)
+
+// pageFetcher fetches one page of a list endpoint.
+type pageFetcher[T any] func(ctx context.Context, opts github.ListOptions) ([]T, *github.Response, error)
+
+type pageResult[T any] struct {
+	items []T
+	resp  *github.Response
+	err   error
+}
+
+// paginate calls fn for every item of a list endpoint in order, as soon as the page of
+// the item arrived. Once the first page reveals the number of pages through its
+// Link rel="last" header, up to concurrency pages are fetched in parallel.
+// Iteration stops at the first error returned by fetch or fn.
+func paginate[T any](ctx context.Context, fetch pageFetcher[T], concurrency int, fn func(T) error) error {
+	items, resp, err := fetch(ctx, github.ListOptions{Page: 1, PerPage: perPage})
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	next := resp.NextPage
+	if next != 0 && resp.LastPage > next && concurrency > 1 {
+		next, err = paginateConcurrently(ctx, fetch, next, resp.LastPage, concurrency, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	for next != 0 {
+		items, resp, err := fetch(ctx, github.ListOptions{Page: next, PerPage: perPage})
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		next = resp.NextPage
+	}
+	return nil
+}
+
+// paginateConcurrently fetches the pages first to last in parallel and passes their items
+// to fn in order. It returns the page following the last one, in case the list grew.
+func paginateConcurrently[T any](ctx context.Context, fetch pageFetcher[T], first, last, concurrency int, fn func(T) error) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan pageResult[T], last+1)
+	for page := first; page <= last; page++ {
+		results[page] = make(chan pageResult[T], 1)
+	}
+
+	// sem bounds the number of pages in flight or waiting to be consumed.
+	sem := make(chan struct{}, concurrency)
+	go func() {
+		for page := first; page <= last; page++ {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(page int) {
+				items, resp, err := fetch(ctx, github.ListOptions{Page: page, PerPage: perPage})
+				results[page] <- pageResult[T]{items: items, resp: resp, err: err}
+			}(page)
+		}
+	}()
+
+	var next int
+	for page := first; page <= last; page++ {
+		var result pageResult[T]
+		select {
+		case result = <-results[page]:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		<-sem
+		if result.err != nil {
+			return 0, result.err
+		}
+		for _, item := range result.items {
+			if err := fn(item); err != nil {
+				return 0, err
+			}
+		}
+		next = result.resp.NextPage
+	}
+	return next, nil
+}
+
+// collect returns all items of a list endpoint.
+func collect[T any](ctx context.Context, fetch pageFetcher[T], concurrency int) ([]T, error) {
+	var all []T
+	err := paginate(ctx, fetch, concurrency, func(item T) error {
+		all = append(all, item)
+		return nil
+	})
+	return all, err
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePages serves pages of consecutive integers like a GitHub list endpoint.
+type fakePages struct {
+	pages    int
+	failPage int
+
+	mux     sync.Mutex
+	fetched []int
+}
+
+func (f *fakePages) fetch(_ context.Context, opts github.ListOptions) ([]int, *github.Response, error) {
+	f.mux.Lock()
+	f.fetched = append(f.fetched, opts.Page)
+	f.mux.Unlock()
+
+	if opts.Page == f.failPage {
+		return nil, nil, errors.New("page failed")
+	}
+	items := make([]int, 0, opts.PerPage)
+	for i := 0; i < opts.PerPage; i++ {
+		items = append(items, (opts.Page-1)*opts.PerPage+i)
+	}
+	resp := &github.Response{LastPage: f.pages}
+	if opts.Page < f.pages {
+		resp.NextPage = opts.Page + 1
+	}
+	return items, resp, nil
+}
+
+func TestPaginate(t *testing.T) {
+	testCases := []struct {
+		pages       int
+		concurrency int
+		failPage    int
+		wantErr     bool
+	}{
+		{pages: 1, concurrency: 4},
+		{pages: 2, concurrency: 4},
+		{pages: 7, concurrency: 1},
+		{pages: 7, concurrency: 3},
+		{pages: 20, concurrency: 20},
+		{pages: 7, concurrency: 3, failPage: 5, wantErr: true},
+		{pages: 7, concurrency: 1, failPage: 5, wantErr: true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			pages := &fakePages{pages: tc.pages, failPage: tc.failPage}
+			items, err := collect(context.Background(), pages.fetch, tc.concurrency)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Len(items, tc.pages*perPage)
+			for j, item := range items {
+				if !assert.Equal(j, item, "items must be in order") {
+					break
+				}
+			}
+			assert.Len(pages.fetched, tc.pages)
+		})
+	}
+}
+
+func TestPaginateStopsOnCallbackError(t *testing.T) {
+	assert := assert.New(t)
+
+	pages := &fakePages{pages: 10}
+	stop := errors.New("stop")
+	var seen int
+	err := paginate(context.Background(), pages.fetch, 2, func(item int) error {
+		if item == perPage+1 {
+			return stop
+		}
+		seen++
+		return nil
+	})
+	assert.ErrorIs(err, stop)
+	assert.Equal(perPage+1, seen)
+}
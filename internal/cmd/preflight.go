@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+)
+
+// requiredPermissionsAnnotation is the cobra annotation under which a command declares
+// the token permissions it needs, as comma separated list of "name:access" pairs
+// using the names of GitHub App permissions, e.g. "actions:write".
+const requiredPermissionsAnnotation = "ghh/required-permissions"
+
+// permissionScopes maps permissions to the classic OAuth scopes that grant them.
+var permissionScopes = map[string][]string{
+	"actions":               {"repo", "public_repo"},
+	"contents":              {"repo", "public_repo"},
+	"workflows":             {"workflow"},
+	"organization_projects": {"project"},
+}
+
+// accessLevels orders the access levels, a higher level implies the lower ones.
+var accessLevels = map[string]int{
+	"read":  1,
+	"write": 2,
+	"admin": 3,
+}
+
+// permissionsProvider is implemented by token sources that know the permissions
+// granted to their tokens.
+type permissionsProvider interface {
+	Permissions() *github.InstallationPermissions
+}
+
+type requiredPermission struct {
+	name   string
+	access string
+}
+
+func (p requiredPermission) String() string {
+	return p.name + ":" + p.access
+}
+
+func requiredPermissions(cmd *cobra.Command) ([]requiredPermission, error) {
+	annotation := cmd.Annotations[requiredPermissionsAnnotation]
+	if annotation == "" {
+		return nil, nil
+	}
+	var perms []requiredPermission
+	for _, entry := range strings.Split(annotation, ",") {
+		name, access, ok := strings.Cut(entry, ":")
+		if !ok || accessLevels[access] == 0 {
+			return nil, fmt.Errorf("invalid permission %q declared by command %s", entry, cmd.Name())
+		}
+		perms = append(perms, requiredPermission{name: name, access: access})
+	}
+	return perms, nil
+}
+
+// errPermissionsUnknown is returned by preflight if the permissions of the token can't
+// be determined.
+var errPermissionsUnknown = errors.New("permissions of token can't be determined")
+
+// preflight checks that the token of the token source grants the permissions the command
+// declared on the given repositories. It must be called before the command mutates anything.
+// Fine-grained personal access tokens don't expose their permissions, so for them the
+// access of the token to each repository is probed instead.
+func preflight(ctx context.Context, cmd *cobra.Command, p *profile, ts oauth2.TokenSource, log loggerI, repos ...repoRef) error {
+	required, err := requiredPermissions(cmd)
+	if err != nil {
+		return err
+	}
+	if len(required) == 0 {
+		return nil
+	}
+
+	token, err := ts.Token()
+	if err != nil {
+		return fmt.Errorf("getting token: %w", err)
+	}
+
+	var missing []string
+	if provider, ok := ts.(permissionsProvider); ok {
+		log.Debugf("preflight: checking installation token permissions")
+		missing = missingPermissions(required, provider.Permissions())
+	} else {
+		info, err := inspectToken(ctx, p, token.AccessToken, log)
+		if err != nil {
+			return err
+		}
+		if !info.hasScopes {
+			return probeRepoPermissions(ctx, cmd, p, ts, info, required, repos, log)
+		}
+		log.Debugf("preflight: checking token scopes %v", info.scopes)
+		missing = missingScopes(required, info.scopes)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("token is missing permissions required by %s: %s", cmd.Name(), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// probeRepoPermissions checks that the token can push to each of the repositories,
+// as reported by the permissions of the repository for the authenticated user.
+func probeRepoPermissions(ctx context.Context, cmd *cobra.Command, p *profile, ts oauth2.TokenSource,
+	info *tokenInfo, required []requiredPermission, repos []repoRef, log loggerI,
+) error {
+	requiredList := make([]string, 0, len(required))
+	for _, perm := range required {
+		requiredList = append(requiredList, perm.String())
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("%w: %s doesn't report its permissions, make sure it grants %s",
+			errPermissionsUnknown, info.tokenType, strings.Join(requiredList, ", "))
+	}
+
+	client, err := newRESTClient(p, oauth2.NewClient(ctx, ts))
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		log.Debugf("preflight: probing permissions of %s on %s", info.tokenType, repo)
+		r, _, err := client.Repositories.Get(ctx, repo.owner, repo.name)
+		if err != nil {
+			return fmt.Errorf("%w: getting repository %s: %s, make sure the token grants %s on it",
+				errPermissionsUnknown, repo, err, strings.Join(requiredList, ", "))
+		}
+		perms := r.GetPermissions()
+		if perms == nil {
+			return fmt.Errorf("%w: no permissions reported for %s, make sure the token grants %s on it",
+				errPermissionsUnknown, repo, strings.Join(requiredList, ", "))
+		}
+		if !perms["push"] && !perms["admin"] {
+			return fmt.Errorf("token can't push to %s, but %s requires %s",
+				repo, cmd.Name(), strings.Join(requiredList, ", "))
+		}
+	}
+	return nil
+}
+
+func missingPermissions(required []requiredPermission, granted *github.InstallationPermissions) []string {
+	grantedList := permissionList(granted)
+	grantedLevels := make(map[string]int, len(grantedList))
+	for _, entry := range grantedList {
+		name, access, _ := strings.Cut(entry, ":")
+		grantedLevels[name] = accessLevels[access]
+	}
+
+	var missing []string
+	for _, perm := range required {
+		if grantedLevels[perm.name] < accessLevels[perm.access] {
+			missing = append(missing, perm.String())
+		}
+	}
+	return missing
+}
+
+func missingScopes(required []requiredPermission, scopes []string) []string {
+	granted := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		granted[scope] = true
+	}
+
+	var missing []string
+outer:
+	for _, perm := range required {
+		alternatives := permissionScopes[perm.name]
+		for _, scope := range alternatives {
+			if granted[scope] {
+				continue outer
+			}
+		}
+		missing = append(missing, fmt.Sprintf("%s (classic scope %s)", perm, strings.Join(alternatives, " or ")))
+	}
+	return missing
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestMissingScopes(t *testing.T) {
+	testCases := []struct {
+		required []requiredPermission
+		scopes   []string
+		expected []string
+	}{
+		{
+			required: []requiredPermission{{name: "actions", access: "write"}},
+			scopes:   []string{"repo"},
+		},
+		{
+			required: []requiredPermission{{name: "contents", access: "write"}, {name: "workflows", access: "write"}},
+			scopes:   []string{"public_repo"},
+			expected: []string{"workflows:write (classic scope workflow)"},
+		},
+		{
+			required: []requiredPermission{{name: "organization_projects", access: "write"}},
+			scopes:   []string{"repo", "read:org"},
+			expected: []string{"organization_projects:write (classic scope project)"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tc.expected, missingScopes(tc.required, tc.scopes))
+		})
+	}
+}
+
+func TestMissingPermissions(t *testing.T) {
+	testCases := []struct {
+		required []requiredPermission
+		granted  *github.InstallationPermissions
+		expected []string
+	}{
+		{
+			required: []requiredPermission{{name: "actions", access: "write"}},
+			granted:  &github.InstallationPermissions{Actions: toPtr("write")},
+		},
+		{
+			required: []requiredPermission{{name: "contents", access: "read"}},
+			granted:  &github.InstallationPermissions{Contents: toPtr("admin")},
+		},
+		{
+			required: []requiredPermission{{name: "actions", access: "write"}, {name: "contents", access: "write"}},
+			granted:  &github.InstallationPermissions{Actions: toPtr("read")},
+			expected: []string{"actions:write", "contents:write"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tc.expected, missingPermissions(tc.required, tc.granted))
+		})
+	}
+}
+
+func TestPreflightFineGrainedToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/user":
+			// Fine-grained tokens don't report OAuth scopes.
+			fmt.Fprint(w, `{"login": "octocat"}`)
+		case "/api/v3/repos/acme/writable":
+			fmt.Fprint(w, `{"name": "writable", "permissions": {"pull": true, "push": true}}`)
+		case "/api/v3/repos/acme/readonly":
+			fmt.Fprint(w, `{"name": "readonly", "permissions": {"pull": true, "push": false}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer server.Close()
+	p := &profile{Host: "ghe.example.com", APIURL: server.URL + "/api/v3/"}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "github_pat_token"})
+	cmd := &cobra.Command{Use: "delete-all-runs", Annotations: map[string]string{requiredPermissionsAnnotation: "actions:write"}}
+
+	testCases := []struct {
+		repos       []repoRef
+		wantErr     string
+		wantUnknown bool
+	}{
+		{ // push access
+			repos: []repoRef{{owner: "acme", name: "writable"}},
+		},
+		{ // read access
+			repos:   []repoRef{{owner: "acme", name: "writable"}, {owner: "acme", name: "readonly"}},
+			wantErr: "token can't push to acme/readonly, but delete-all-runs requires actions:write",
+		},
+		{ // repository not accessible
+			repos:       []repoRef{{owner: "acme", name: "private"}},
+			wantErr:     "make sure the token grants actions:write on it",
+			wantUnknown: true,
+		},
+		{ // no repository
+			wantErr:     "make sure it grants actions:write",
+			wantUnknown: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := preflight(context.Background(), cmd, p, ts, newLogger(false), tc.repos...)
+			if tc.wantErr == "" {
+				assert.NoError(err)
+				return
+			}
+			assert.ErrorContains(err, tc.wantErr)
+			assert.Equal(tc.wantUnknown, errors.Is(err, errPermissionsUnknown))
+		})
+	}
+}
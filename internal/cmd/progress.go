@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	progressBarWidth      = 30
+	progressRenderPeriod  = 200 * time.Millisecond
+	progressMinRateWindow = time.Second
+)
+
+// progressBar renders the progress of a long running operation with rate and ETA
+// to stderr. It is only rendered if stderr is a terminal.
+type progressBar struct {
+	out     io.Writer
+	label   string
+	enabled bool
+	now     func() time.Time
+
+	mux        sync.Mutex
+	total      int
+	done       int
+	failed     int
+	start      time.Time
+	lastRender time.Time
+}
+
+func newProgressBar(label string, total int) *progressBar {
+	return &progressBar{
+		out:     os.Stderr,
+		label:   label,
+		enabled: term.IsTerminal(int(os.Stderr.Fd())),
+		now:     time.Now,
+		total:   total,
+		start:   time.Now(),
+	}
+}
+
+// SetTotal updates the expected number of items.
+func (p *progressBar) SetTotal(total int) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.total = total
+	p.render(false)
+}
+
+// Increment counts a processed item.
+func (p *progressBar) Increment(failed bool) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.done++
+	if failed {
+		p.failed++
+	}
+	p.render(false)
+}
+
+// Finish renders the final state and ends the line.
+func (p *progressBar) Finish() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.render(true)
+	if p.enabled {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// String returns the current progress, e.g. "120/5000 (2 failed) 12.3/s ETA 6m30s".
+func (p *progressBar) String() string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.status()
+}
+
+func (p *progressBar) render(force bool) {
+	if !p.enabled {
+		return
+	}
+	now := p.now()
+	if !force && now.Sub(p.lastRender) < progressRenderPeriod {
+		return
+	}
+	p.lastRender = now
+
+	total := max(p.total, p.done)
+	filled := progressBarWidth
+	if total > 0 {
+		filled = progressBarWidth * p.done / total
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(p.out, "\r\033[K%s [%s] %s", p.label, bar, p.status())
+}
+
+func (p *progressBar) status() string {
+	total := max(p.total, p.done)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d/%d", p.done, total)
+	if p.failed > 0 {
+		fmt.Fprintf(&b, " (%d failed)", p.failed)
+	}
+
+	elapsed := p.now().Sub(p.start)
+	if elapsed < progressMinRateWindow || p.done == 0 {
+		return b.String()
+	}
+	rate := float64(p.done) / elapsed.Seconds()
+	fmt.Fprintf(&b, " %.1f/s", rate)
+	if remaining := total - p.done; remaining > 0 {
+		eta := time.Duration(float64(remaining) / rate * float64(time.Second))
+		fmt.Fprintf(&b, " ETA %s", eta.Round(time.Second))
+	}
+	return b.String()
+}
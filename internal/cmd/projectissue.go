@@ -5,8 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
-	"github.com/katexochen/ghh/internal/logger"
 	"github.com/shurcooL/githubv4"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +18,9 @@ func NewCreateProjectIssueCmd() *cobra.Command {
 		Use:   "create-project-issue",
 		Short: "Create a project issue",
 		RunE:  createProjectIssue,
+		Annotations: map[string]string{
+			requiredPermissionsAnnotation: "organization_projects:write",
+		},
 	}
 	cmd.Flags().String("metadata", "", "Path to metadata file")
 	cmd.Flags().String("body", "", "Path to body file")
@@ -41,19 +45,26 @@ func createProjectIssue(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	var log loggerI
-	if flags.verbose {
-		log = &logger.VerboseLogger{}
-	} else {
-		log = &logger.DefaultLogger{}
+	log := newLogger(flags.verbose)
+
+	p, err := resolveProfile(cmd)
+	if err != nil {
+		return err
 	}
 
-	token, err := getToken()
+	ts, err := newTokenSource(cmd.Context(), p, flags.Metadata.owner, log)
 	if err != nil {
 		return fmt.Errorf("getting token: %w", err)
 	}
 
-	c := newGithubV4Client(cmd.Context(), token, log)
+	c := newGithubV4Client(p, ts, log, flags.dryRun)
+
+	// The permission on the project is checked below if the token doesn't report its permissions.
+	if err := preflight(cmd.Context(), cmd, p, ts, log); errors.Is(err, errPermissionsUnknown) {
+		log.Debugf("preflight: %s", err)
+	} else if err != nil {
+		return err
+	}
 
 	c.logger.Debugf("searching project %s/%d", flags.Metadata.Organization, flags.Metadata.ProjectNumber)
 	isOrg := flags.Metadata.Organization != ""
@@ -62,6 +73,9 @@ func createProjectIssue(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("querying project: %w", err)
 	}
 	c.logger.PrintJSON("found project", project)
+	if !project.ViewerCanUpdate {
+		return fmt.Errorf("token is not allowed to update project %q, check the project permissions of the token", project.Title)
+	}
 
 	var assigneeIDs []githubv4.ID
 	for _, assignee := range flags.Metadata.Assignees {
@@ -94,6 +108,10 @@ func createProjectIssue(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("updating project issue fields: %w", err)
 	}
 
+	if flags.dryRun {
+		return printProjectIssuePreview(project, flags)
+	}
+
 	itemURL := fmt.Sprintf("%s?pane=issue&itemId=%d", project.URL, item.DatabaseID)
 	c.logger.Infof("created project issue:")
 	fmt.Println(itemURL)
@@ -101,10 +119,32 @@ func createProjectIssue(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
+// printProjectIssuePreview prints the draft issue that would be created.
+func printProjectIssuePreview(project *Project, flags createProjectIssueFlags) error {
+	fieldNames := make([]string, 0, len(flags.Metadata.Fields))
+	for name := range flags.Metadata.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+	var fields []string
+	for _, name := range fieldNames {
+		fields = append(fields, fmt.Sprintf("%s=%s", name, flags.Metadata.Fields[name]))
+	}
+
+	fmt.Println("Would create project issue:")
+	return printTable(os.Stdout, []string{"PROJECT", "TITLE", "ASSIGNEES", "FIELDS"}, [][]string{{
+		string(project.Title),
+		flags.Metadata.IssueTitle,
+		strings.Join(flags.Metadata.Assignees, ","),
+		strings.Join(fields, ","),
+	}})
+}
+
 type createProjectIssueFlags struct {
 	Metadata metadata
 	Body     string
 	verbose  bool
+	dryRun   bool
 }
 
 func parseCreateProjectIssueFlags(cmd *cobra.Command) (createProjectIssueFlags, error) {
@@ -149,10 +189,16 @@ func parseCreateProjectIssueFlags(cmd *cobra.Command) (createProjectIssueFlags,
 		return createProjectIssueFlags{}, err
 	}
 
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return createProjectIssueFlags{}, err
+	}
+
 	return createProjectIssueFlags{
 		Metadata: metadata,
 		Body:     string(bodyBytes),
 		verbose:  verbose,
+		dryRun:   dryRun,
 	}, nil
 }
 
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pruneTarget is an item selected for deletion by a prune command.
+type pruneTarget struct {
+	id   int64
+	size int64
+	// row holds the columns of the item in the preview table, without the size.
+	row []string
+}
+
+// pruner deletes the items selected by a prune command concurrently and
+// reports the reclaimed storage.
+type pruner struct {
+	// noun is the singular name of the items, e.g. "artifact".
+	noun string
+	// header holds the columns of the preview table, without the size.
+	header      []string
+	repoName    string
+	concurrency int
+	dryRun      bool
+	yes         bool
+	delete      func(ctx context.Context, id int64) error
+	log         loggerI
+}
+
+// prune previews the targets and deletes them after confirmation.
+func (p *pruner) prune(ctx context.Context, targets []pruneTarget) error {
+	if len(targets) == 0 {
+		fmt.Printf("No %ss to delete.\n", p.noun)
+		return nil
+	}
+
+	var total int64
+	rows := make([][]string, 0, len(targets))
+	for _, t := range targets {
+		total += t.size
+		rows = append(rows, append(t.row[:len(t.row):len(t.row)], formatBytes(t.size)))
+	}
+	header := append(p.header[:len(p.header):len(p.header)], "SIZE")
+
+	if p.dryRun {
+		fmt.Printf("Would delete %d %ss, reclaiming %s:\n", len(targets), p.noun, formatBytes(total))
+		return printTable(os.Stdout, header, rows)
+	}
+	if !p.yes {
+		if err := printTable(os.Stdout, header, rows); err != nil {
+			return err
+		}
+		action := fmt.Sprintf("Delete %d %ss of %s, reclaiming %s", len(targets), p.noun, p.repoName, formatBytes(total))
+		ok, err := confirmTyped(action, p.repoName)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	progress := newProgressBar(fmt.Sprintf("Deleting %ss", p.noun), len(targets))
+	var mux sync.Mutex
+	var deleted int
+	var reclaimed int64
+	failed := map[int64]error{}
+	pool := newWorkerPool(ctx, p.concurrency, func(ctx context.Context, t pruneTarget) {
+		err := p.delete(ctx, t.id)
+		gone := errors.Is(err, ErrNotFound)
+		if ctx.Err() != nil {
+			return
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+		switch {
+		case gone:
+			// Deleted in the meantime, e.g. by expiry.
+			deleted++
+		case err != nil:
+			p.log.Debugf("deleting %s %d: %s", p.noun, t.id, err)
+			failed[t.id] = err
+		default:
+			deleted++
+			reclaimed += t.size
+		}
+		progress.Increment(err != nil && !gone)
+	})
+	for _, t := range targets {
+		if err := pool.Submit(ctx, t); err != nil {
+			break
+		}
+	}
+	pool.Wait()
+	progress.Finish()
+
+	if ctx.Err() != nil {
+		fmt.Printf("Interrupted after deleting %d %ss, reclaimed %s.\n", deleted, p.noun, formatBytes(reclaimed))
+		return ctx.Err()
+	}
+	fmt.Printf("Deleted %d %ss, reclaimed %s.\n", deleted, p.noun, formatBytes(reclaimed))
+	if len(failed) > 0 {
+		return deletionFailures(p.noun, failed)
+	}
+	return nil
+}
+
+// deletionFailures summarizes failed deletions by ID, listing the first few.
+func deletionFailures(noun string, failed map[int64]error) error {
+	const maxListed = 5
+	ids := make([]int64, 0, len(failed))
+	for id := range failed {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	errs := []error{fmt.Errorf("failed to delete %d %ss", len(ids), noun)}
+	for _, id := range ids[:min(len(ids), maxListed)] {
+		errs = append(errs, fmt.Errorf("%s %d: %w", noun, id, failed[id]))
+	}
+	if len(ids) > maxListed {
+		errs = append(errs, fmt.Errorf("and %d more", len(ids)-maxListed))
+	}
+	return errors.Join(errs...)
+}
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"B", 1},
+}
+
+// formatBytes formats a size with decimal units, e.g. '1.5 GB'.
+func formatBytes(n int64) string {
+	for _, unit := range byteUnits[:4] {
+		if n >= unit.size {
+			return fmt.Sprintf("%.1f %s", float64(n)/float64(unit.size), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// parseSize parses a size like '500MB', '1.5GB' or '64KiB'. Without a unit, the size is in bytes.
+func parseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	upper := strings.ToUpper(trimmed)
+	unitSize := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(upper, strings.ToUpper(unit.suffix)) {
+			unitSize = unit.size
+			trimmed = strings.TrimSpace(trimmed[:len(trimmed)-len(unit.suffix)])
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unitSize)), nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "512", want: 512},             // bytes
+		{size: "512B", want: 512},            // bytes with unit
+		{size: "100MB", want: 100_000_000},   // megabytes
+		{size: "1.5GB", want: 1_500_000_000}, // fractional
+		{size: "64KiB", want: 64 << 10},      // binary unit
+		{size: "2 gib", want: 2 << 30},       // lower case
+		{size: "lots", wantErr: true},        // invalid
+		{size: "-1MB", wantErr: true},        // negative
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			size, err := parseSize(tc.size)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, size)
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("999 B", formatBytes(999))
+	assert.Equal("1.5 KB", formatBytes(1500))
+	assert.Equal("10.0 GB", formatBytes(10_000_000_000))
+}
+
+func TestPrunerReportsReclaimedBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	var mux sync.Mutex
+	var deleted []int64
+	pr := &pruner{
+		noun:        "cache",
+		concurrency: 2,
+		yes:         true,
+		log:         newLogger(false),
+		delete: func(_ context.Context, id int64) error {
+			switch id {
+			case 2:
+				return ErrNotFound
+			case 3:
+				return errors.New("boom")
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			deleted = append(deleted, id)
+			return nil
+		},
+	}
+
+	err := pr.prune(context.Background(), []pruneTarget{
+		{id: 1, size: 100},
+		{id: 2, size: 200},
+		{id: 3, size: 300},
+	})
+	assert.ErrorContains(err, "failed to delete 1 caches")
+	assert.ErrorContains(err, "cache 3: boom")
+	assert.Equal([]int64{1}, deleted)
+}
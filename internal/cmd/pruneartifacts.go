@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+)
+
+// NewPruneArtifactsCmd creates a new command for deleting workflow artifacts.
+func NewPruneArtifactsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune-artifacts",
+		Short: "Delete workflow artifacts",
+		Long: `
+Delete the workflow artifacts of the repository to free Actions storage.
+
+Without filters, all artifacts are deleted. Expired artifacts don't use storage
+anymore and are skipped.
+		`,
+		RunE: pruneArtifacts,
+		Annotations: map[string]string{
+			requiredPermissionsAnnotation: "actions:write",
+		},
+	}
+	cmd.Flags().StringSlice(
+		"name",
+		nil,
+		"Only delete artifacts with a name matching the glob pattern, e.g. 'coverage-*'. Can be repeated.",
+	)
+	cmd.Flags().String(
+		"older-than",
+		"",
+		"Only delete artifacts older than the age, e.g. '30d', '2w' or '12h'",
+	)
+	cmd.Flags().String(
+		"larger-than",
+		"",
+		"Only delete artifacts larger than the size, e.g. '100MB' or '1GiB'",
+	)
+	cmd.Flags().Int(
+		"keep-latest-per-name",
+		0,
+		"Keep the newest N artifacts of every name matching '--name', 0 keeps none",
+	)
+	cmd.Flags().String(
+		"repo",
+		"",
+		"Repository to delete the artifacts of, as [HOST/]OWNER/REPO. "+
+			"Defaults to the repository of the current directory.",
+	)
+	cmd.Flags().BoolP(
+		"yes",
+		"y",
+		false,
+		"Don't ask for confirmation",
+	)
+	cmd.Flags().Int(
+		"concurrency",
+		8,
+		"Number of artifacts deleted in parallel",
+	)
+	return cmd
+}
+
+func pruneArtifacts(cmd *cobra.Command, _ []string) error {
+	flags, err := parsePruneArtifactsFlags(cmd, time.Now())
+	if err != nil {
+		return err
+	}
+	log := newLogger(flags.verbose)
+
+	repo, err := findRepo(cmd, flags.repo)
+	if err != nil {
+		return err
+	}
+
+	p, err := resolveProfileForRepo(cmd, repo.host)
+	if err != nil {
+		return err
+	}
+
+	ts, err := newTokenSource(cmd.Context(), p, repo.owner, log)
+	if err != nil {
+		return err
+	}
+
+	c, err := newGithubClient(repo.owner, repo.name, p, ts, log, flags.dryRun)
+	if err != nil {
+		return err
+	}
+
+	if err := preflight(cmd.Context(), cmd, p, ts, log, repo); err != nil {
+		return err
+	}
+
+	log.Debugf("listing artifacts")
+	artifacts, err := c.GetArtifacts(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("listing artifacts: %w", err)
+	}
+	log.Debugf("%d artifacts found", len(artifacts))
+
+	var targets []pruneTarget
+	for _, a := range selectArtifacts(artifacts, flags.filter) {
+		targets = append(targets, pruneTarget{
+			id:   a.GetID(),
+			size: a.GetSizeInBytes(),
+			row: []string{
+				strconv.FormatInt(a.GetID(), 10),
+				a.GetName(),
+				a.GetCreatedAt().Format(time.DateTime),
+				a.GetWorkflowRun().GetHeadBranch(),
+			},
+		})
+	}
+
+	pr := &pruner{
+		noun:        "artifact",
+		header:      []string{"ARTIFACT", "NAME", "CREATED", "BRANCH"},
+		repoName:    repo.String(),
+		concurrency: flags.concurrency,
+		dryRun:      flags.dryRun,
+		yes:         flags.yes,
+		delete:      c.DeleteArtifact,
+		log:         log,
+	}
+	return pr.prune(cmd.Context(), targets)
+}
+
+// artifactFilter selects the artifacts to delete.
+type artifactFilter struct {
+	// names holds glob patterns of which the name must match one, if set.
+	names []string
+	// createdBefore selects artifacts created before the time, if set.
+	createdBefore time.Time
+	// largerThan selects artifacts larger than the size in bytes, if set.
+	largerThan int64
+	// keepLatestPerName is the number of newest artifacts per name that are kept.
+	keepLatestPerName int
+}
+
+// selectArtifacts returns the unexpired artifacts matching the filter, newest first.
+// The newest artifacts of every name are kept regardless of their age and size.
+func selectArtifacts(artifacts []*github.Artifact, f artifactFilter) []*github.Artifact {
+	sorted := make([]*github.Artifact, len(artifacts))
+	copy(sorted, artifacts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetCreatedAt().After(sorted[j].GetCreatedAt().Time)
+	})
+
+	var selected []*github.Artifact
+	perName := map[string]int{}
+	for _, a := range sorted {
+		if a.GetExpired() || !matchesAnyGlob(a.GetName(), f.names) {
+			continue
+		}
+		perName[a.GetName()]++
+		if perName[a.GetName()] <= f.keepLatestPerName {
+			continue
+		}
+		if !f.createdBefore.IsZero() && !a.GetCreatedAt().Before(f.createdBefore) {
+			continue
+		}
+		if f.largerThan > 0 && a.GetSizeInBytes() <= f.largerThan {
+			continue
+		}
+		selected = append(selected, a)
+	}
+	return selected
+}
+
+// matchesAnyGlob reports whether the name matches one of the glob patterns.
+// Without patterns, every name matches.
+func matchesAnyGlob(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type pruneArtifactsFlags struct {
+	verbose     bool
+	dryRun      bool
+	filter      artifactFilter
+	repo        string
+	yes         bool
+	concurrency int
+}
+
+func parsePruneArtifactsFlags(cmd *cobra.Command, now time.Time) (*pruneArtifactsFlags, error) {
+	flags := &pruneArtifactsFlags{}
+
+	var err error
+	flags.verbose, err = cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return nil, err
+	}
+	flags.dryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
+	flags.filter.names, err = cmd.Flags().GetStringSlice("name")
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range flags.filter.names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+	}
+	olderThan, err := cmd.Flags().GetString("older-than")
+	if err != nil {
+		return nil, err
+	}
+	if olderThan != "" {
+		age, err := parseAge(olderThan)
+		if err != nil {
+			return nil, fmt.Errorf("parsing '--older-than': %w", err)
+		}
+		flags.filter.createdBefore = now.Add(-age)
+	}
+	largerThan, err := cmd.Flags().GetString("larger-than")
+	if err != nil {
+		return nil, err
+	}
+	if largerThan != "" {
+		flags.filter.largerThan, err = parseSize(largerThan)
+		if err != nil {
+			return nil, fmt.Errorf("parsing '--larger-than': %w", err)
+		}
+	}
+	flags.filter.keepLatestPerName, err = cmd.Flags().GetInt("keep-latest-per-name")
+	if err != nil {
+		return nil, err
+	}
+	if flags.filter.keepLatestPerName < 0 {
+		return nil, errors.New("'--keep-latest-per-name' must not be negative")
+	}
+	flags.repo, err = cmd.Flags().GetString("repo")
+	if err != nil {
+		return nil, err
+	}
+	flags.yes, err = cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
+	}
+	flags.concurrency, err = cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return nil, err
+	}
+	if flags.concurrency < 1 {
+		return nil, errors.New("'--concurrency' must be at least 1")
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectArtifacts(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	artifact := func(id int64, name string, age time.Duration, size int64) *github.Artifact {
+		return &github.Artifact{
+			ID:          toPtr(id),
+			Name:        toPtr(name),
+			CreatedAt:   &github.Timestamp{Time: now.Add(-age)},
+			SizeInBytes: toPtr(size),
+		}
+	}
+	// Not sorted, to check that the newest are kept.
+	artifacts := []*github.Artifact{
+		artifact(1, "coverage-linux", 3*24*time.Hour, 10),
+		artifact(2, "coverage-linux", time.Hour, 10),
+		artifact(3, "logs", 2*24*time.Hour, 1000),
+		artifact(4, "coverage-linux", 5*24*time.Hour, 2000),
+		artifact(5, "coverage-mac", 4*24*time.Hour, 10),
+		artifact(7, "empty", 6*24*time.Hour, 0),
+		{ID: toPtr(int64(6)), Name: toPtr("logs"), Expired: toPtr(true)},
+	}
+
+	testCases := []struct {
+		filter  artifactFilter
+		wantIDs []int64
+	}{
+		{ // all unexpired, newest first
+			wantIDs: []int64{2, 3, 1, 5, 4, 7},
+		},
+		{ // name pattern
+			filter:  artifactFilter{names: []string{"coverage-*"}},
+			wantIDs: []int64{2, 1, 5, 4},
+		},
+		{ // keep latest per name
+			filter:  artifactFilter{keepLatestPerName: 1},
+			wantIDs: []int64{1, 4},
+		},
+		{ // older than
+			filter:  artifactFilter{createdBefore: now.Add(-49 * time.Hour)},
+			wantIDs: []int64{1, 5, 4, 7},
+		},
+		{ // larger than
+			filter:  artifactFilter{largerThan: 100},
+			wantIDs: []int64{3, 4},
+		},
+		{ // keep latest regardless of age
+			filter:  artifactFilter{names: []string{"coverage-linux"}, keepLatestPerName: 2, createdBefore: now},
+			wantIDs: []int64{4},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			var ids []int64
+			for _, a := range selectArtifacts(artifacts, tc.filter) {
+				ids = append(ids, a.GetID())
+			}
+			assert.Equal(tc.wantIDs, ids)
+		})
+	}
+}
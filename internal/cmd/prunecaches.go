@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+)
+
+// pullRequestRefPattern matches the refs of pull request runs, e.g. refs/pull/42/merge.
+var pullRequestRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/(merge|head)$`)
+
+// NewPruneCachesCmd creates a new command for deleting Actions caches.
+func NewPruneCachesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune-caches",
+		Short: "Delete Actions caches",
+		Long: `
+Delete the Actions caches of the repository to stay below the cache size limit.
+
+Without filters, all caches are deleted. With '--closed-prs', only the caches of
+pull requests that are closed or merged are deleted, as they can't be restored anymore.
+		`,
+		RunE: pruneCaches,
+		Annotations: map[string]string{
+			requiredPermissionsAnnotation: "actions:write",
+		},
+	}
+	cmd.Flags().String(
+		"key-prefix",
+		"",
+		"Only delete caches with a key starting with the prefix",
+	)
+	cmd.Flags().String(
+		"ref",
+		"",
+		"Only delete caches of the ref, e.g. 'refs/heads/main' or 'refs/pull/42/merge'",
+	)
+	cmd.Flags().Bool(
+		"closed-prs",
+		false,
+		"Only delete caches of closed pull requests",
+	)
+	cmd.Flags().String(
+		"repo",
+		"",
+		"Repository to delete the caches of, as [HOST/]OWNER/REPO. "+
+			"Defaults to the repository of the current directory.",
+	)
+	cmd.Flags().BoolP(
+		"yes",
+		"y",
+		false,
+		"Don't ask for confirmation",
+	)
+	cmd.Flags().Int(
+		"concurrency",
+		8,
+		"Number of caches deleted in parallel",
+	)
+	return cmd
+}
+
+func pruneCaches(cmd *cobra.Command, _ []string) error {
+	flags, err := parsePruneCachesFlags(cmd)
+	if err != nil {
+		return err
+	}
+	log := newLogger(flags.verbose)
+
+	repo, err := findRepo(cmd, flags.repo)
+	if err != nil {
+		return err
+	}
+
+	p, err := resolveProfileForRepo(cmd, repo.host)
+	if err != nil {
+		return err
+	}
+
+	ts, err := newTokenSource(cmd.Context(), p, repo.owner, log)
+	if err != nil {
+		return err
+	}
+
+	c, err := newGithubClient(repo.owner, repo.name, p, ts, log, flags.dryRun)
+	if err != nil {
+		return err
+	}
+
+	if err := preflight(cmd.Context(), cmd, p, ts, log, repo); err != nil {
+		return err
+	}
+
+	log.Debugf("listing caches")
+	caches, err := c.GetCaches(cmd.Context(), flags.keyPrefix, flags.ref)
+	if err != nil {
+		return fmt.Errorf("listing caches: %w", err)
+	}
+	log.Debugf("%d caches found", len(caches))
+
+	if flags.closedPRs {
+		caches, err = selectClosedPRCaches(cmd.Context(), c, caches, log)
+		if err != nil {
+			return err
+		}
+	}
+
+	var targets []pruneTarget
+	for _, cache := range caches {
+		targets = append(targets, pruneTarget{
+			id:   cache.GetID(),
+			size: cache.GetSizeInBytes(),
+			row: []string{
+				strconv.FormatInt(cache.GetID(), 10),
+				cache.GetKey(),
+				cache.GetRef(),
+				cache.GetLastAccessedAt().Format(time.DateTime),
+			},
+		})
+	}
+
+	pr := &pruner{
+		noun:        "cache",
+		header:      []string{"CACHE", "KEY", "REF", "LAST ACCESSED"},
+		repoName:    repo.String(),
+		concurrency: flags.concurrency,
+		dryRun:      flags.dryRun,
+		yes:         flags.yes,
+		delete:      c.DeleteCache,
+		log:         log,
+	}
+	return pr.prune(cmd.Context(), targets)
+}
+
+// selectClosedPRCaches returns the caches belonging to closed pull requests.
+// The state of every pull request is looked up once. Pull requests that don't exist
+// anymore, e.g. from deleted forks, are treated as closed. Caches of pull requests
+// whose state can't be looked up are skipped.
+func selectClosedPRCaches(ctx context.Context, c *githubClient, caches []*github.ActionsCache, log loggerI,
+) ([]*github.ActionsCache, error) {
+	closed := map[int]bool{}
+	var selected []*github.ActionsCache
+	for _, cache := range caches {
+		number, ok := pullRequestNumber(cache.GetRef())
+		if !ok {
+			continue
+		}
+		isClosed, known := closed[number]
+		if !known {
+			pr, err := c.GetPullRequest(ctx, number)
+			switch {
+			case errors.Is(err, ErrNotFound):
+				isClosed = true
+				log.Debugf("pull request #%d not found, treating it as closed", number)
+			case ctx.Err() != nil:
+				return nil, ctx.Err()
+			case err != nil:
+				log.Warnf("skipping cache %d: %s", cache.GetID(), err)
+				continue
+			default:
+				isClosed = pr.GetState() == "closed"
+				log.Debugf("pull request #%d is %s", number, pr.GetState())
+			}
+			closed[number] = isClosed
+		}
+		if isClosed {
+			selected = append(selected, cache)
+		}
+	}
+	return selected, nil
+}
+
+// pullRequestNumber returns the number of the pull request the ref belongs to.
+func pullRequestNumber(ref string) (int, bool) {
+	match := pullRequestRefPattern.FindStringSubmatch(ref)
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+type pruneCachesFlags struct {
+	verbose     bool
+	dryRun      bool
+	keyPrefix   string
+	ref         string
+	closedPRs   bool
+	repo        string
+	yes         bool
+	concurrency int
+}
+
+func parsePruneCachesFlags(cmd *cobra.Command) (*pruneCachesFlags, error) {
+	flags := &pruneCachesFlags{}
+
+	var err error
+	flags.verbose, err = cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return nil, err
+	}
+	flags.dryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
+	flags.keyPrefix, err = cmd.Flags().GetString("key-prefix")
+	if err != nil {
+		return nil, err
+	}
+	flags.ref, err = cmd.Flags().GetString("ref")
+	if err != nil {
+		return nil, err
+	}
+	flags.closedPRs, err = cmd.Flags().GetBool("closed-prs")
+	if err != nil {
+		return nil, err
+	}
+	flags.repo, err = cmd.Flags().GetString("repo")
+	if err != nil {
+		return nil, err
+	}
+	flags.yes, err = cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
+	}
+	flags.concurrency, err = cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return nil, err
+	}
+	if flags.concurrency < 1 {
+		return nil, errors.New("'--concurrency' must be at least 1")
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestNumber(t *testing.T) {
+	testCases := []struct {
+		ref    string
+		want   int
+		wantOK bool
+	}{
+		{ref: "refs/pull/42/merge", want: 42, wantOK: true}, // merge ref
+		{ref: "refs/pull/7/head", want: 7, wantOK: true},    // head ref
+		{ref: "refs/heads/main"},                            // branch
+		{ref: "refs/tags/v1.0.0"},                           // tag
+		{ref: "refs/pull/abc/merge"},                        // not number
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			number, ok := pullRequestNumber(tc.ref)
+			assert.Equal(tc.wantOK, ok)
+			assert.Equal(tc.want, number)
+		})
+	}
+}
+
+func TestSelectClosedPRCaches(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/pulls/1":
+			fmt.Fprint(w, `{"number": 1, "state": "closed"}`)
+		case "/api/v3/repos/owner/repo/pulls/2":
+			fmt.Fprint(w, `{"number": 2, "state": "open"}`)
+		case "/api/v3/repos/owner/repo/pulls/3":
+			// Pull request of a deleted fork.
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "Validation Failed"}`)
+		}
+	}))
+
+	cache := func(id int64, ref string) *github.ActionsCache {
+		return &github.ActionsCache{ID: toPtr(id), Ref: toPtr(ref)}
+	}
+	caches := []*github.ActionsCache{
+		cache(10, "refs/pull/1/merge"),
+		cache(11, "refs/heads/main"),
+		cache(20, "refs/pull/2/merge"),
+		cache(30, "refs/pull/3/merge"),
+		cache(40, "refs/pull/4/merge"),
+		cache(12, "refs/pull/1/head"),
+	}
+
+	selected, err := selectClosedPRCaches(context.Background(), c, caches, newLogger(false))
+	require.NoError(err)
+	var ids []int64
+	for _, cache := range selected {
+		ids = append(ids, cache.GetID())
+	}
+	assert.Equal([]int64{10, 30, 12}, ids)
+}
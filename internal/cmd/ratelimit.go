@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	rateLimitResetHeader     = "X-RateLimit-Reset"
+	rateLimitResourceHeader  = "X-RateLimit-Resource"
+	retryAfterHeader         = "Retry-After"
+
+	defaultMaxRetries = 5
+	// defaultBaseBackoff is the first backoff after a server or network error.
+	defaultBaseBackoff = 2 * time.Second
+	// defaultSecondaryBackoff is the first backoff after a secondary rate limit without
+	// Retry-After header. GitHub asks to wait at least a minute in that case.
+	defaultSecondaryBackoff = time.Minute
+	defaultMaxBackoff       = 2 * time.Minute
+)
+
+// rateLimitTransports holds the rateLimitTransport of every host, shared by all clients.
+var (
+	rateLimitTransportsMux sync.Mutex
+	rateLimitTransports    = map[string]*rateLimitTransport{}
+)
+
+// newHTTPClient creates an HTTP client that authenticates with the token source and
+// handles rate limits with the rateLimitTransport of the profile's host. All clients of
+// a host share the transport, so they wait for the same exhausted rate limits.
+func newHTTPClient(p *profile, ts oauth2.TokenSource, log loggerI) *http.Client {
+	rateLimitTransportsMux.Lock()
+	defer rateLimitTransportsMux.Unlock()
+	transport, ok := rateLimitTransports[p.Host]
+	if !ok {
+		transport = newRateLimitTransport(http.DefaultTransport, log)
+		rateLimitTransports[p.Host] = transport
+	}
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: ts,
+			Base:   transport,
+		},
+	}
+}
+
+// rateLimitTransport is an http.RoundTripper that handles the rate limits of the GitHub API, see
+// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api.
+//
+// When the primary rate limit is exhausted, all requests with the same token to the same
+// resource are paused until the limit resets. Requests hitting a secondary rate limit are
+// retried after the Retry-After duration or with exponential backoff. Idempotent requests
+// and GraphQL queries failing with a server error are retried the same way.
+type rateLimitTransport struct {
+	base        http.RoundTripper
+	log         loggerI
+	maxRetries  int
+	baseBackoff time.Duration
+	// secondaryBackoff is the base backoff for secondary rate limits.
+	secondaryBackoff time.Duration
+	maxBackoff       time.Duration
+	now              func() time.Time
+	sleep            func(context.Context, time.Duration) error
+
+	mux sync.Mutex
+	// pausedUntil holds the reset time of exhausted rate limits, by token and resource.
+	pausedUntil map[string]time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, log loggerI) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:             base,
+		log:              log,
+		maxRetries:       defaultMaxRetries,
+		baseBackoff:      defaultBaseBackoff,
+		secondaryBackoff: defaultSecondaryBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		now:              time.Now,
+		sleep:            sleepContext,
+		pausedUntil:      map[string]time.Time{},
+	}
+}
+
+// RoundTrip executes the request, waiting for and retrying on rate limits.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	resource := requestResource(req)
+	limit := rateLimitKey(req, resource)
+	retryable := isRetryable(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForReset(ctx, limit, resource); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		canRetry := attempt < t.maxRetries && (req.Body == nil || req.GetBody != nil)
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			if ctx.Err() != nil || !canRetry || !retryable {
+				return nil, err
+			}
+			t.log.Warnf("%s %s: %s, retrying", req.Method, req.URL.Path, err)
+			if err := t.sleep(ctx, t.backoff(t.baseBackoff, attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.Header.Get(rateLimitRemainingHeader) == "0" {
+			exhausted := resp.Header.Get(rateLimitResourceHeader)
+			if exhausted == "" {
+				exhausted = resource
+			}
+			t.pause(rateLimitKey(req, exhausted), resp.Header.Get(rateLimitResetHeader))
+		}
+
+		wait, retry := t.classify(req, resp, retryable, attempt)
+		if !retry || !canRetry {
+			return resp, nil
+		}
+		drainBody(resp)
+		if wait > 0 {
+			if err := t.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// classify decides whether the response should be retried and how long to wait before.
+func (t *rateLimitTransport) classify(req *http.Request, resp *http.Response, retryable bool, attempt int) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if resp.Header.Get(rateLimitRemainingHeader) == "0" {
+			reset := t.resetTime(resp.Header.Get(rateLimitResetHeader))
+			t.log.Warnf("primary rate limit exceeded, waiting until %s", reset.Local().Format(time.TimeOnly))
+			return max(reset.Sub(t.now()), 0), true
+		}
+		if wait, ok := retryAfter(resp); ok {
+			t.log.Warnf("secondary rate limit exceeded, retrying in %s", wait)
+			return wait, true
+		}
+		if isSecondaryRateLimit(resp) {
+			wait := t.backoff(t.secondaryBackoff, attempt)
+			t.log.Warnf("secondary rate limit exceeded, retrying in %s", wait)
+			return wait, true
+		}
+		return 0, false
+	case resp.StatusCode >= http.StatusInternalServerError && retryable:
+		wait, ok := retryAfter(resp)
+		if !ok {
+			wait = t.backoff(t.baseBackoff, attempt)
+		}
+		t.log.Warnf("%s %s: %s, retrying in %s", req.Method, req.URL.Path, resp.Status, wait)
+		return wait, true
+	default:
+		return 0, false
+	}
+}
+
+// retryAfter returns the duration of the Retry-After header, if it is set.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(resp.Header.Get(retryAfterHeader))
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func (t *rateLimitTransport) backoff(base time.Duration, attempt int) time.Duration {
+	wait := base << attempt
+	if wait <= 0 || wait > t.maxBackoff {
+		wait = t.maxBackoff
+	}
+	return wait
+}
+
+func (t *rateLimitTransport) resetTime(reset string) time.Time {
+	epoch, err := strconv.ParseInt(reset, 10, 64)
+	if err != nil {
+		// Without a reset time, wait for a minute, as GitHub suggests.
+		return t.now().Add(time.Minute)
+	}
+	// Add a second to not race the reset.
+	return time.Unix(epoch, 0).Add(time.Second)
+}
+
+func (t *rateLimitTransport) pause(limit, reset string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.pausedUntil[limit] = t.resetTime(reset)
+}
+
+func (t *rateLimitTransport) waitForReset(ctx context.Context, limit, resource string) error {
+	t.mux.Lock()
+	until, ok := t.pausedUntil[limit]
+	t.mux.Unlock()
+	if !ok {
+		return nil
+	}
+	if wait := until.Sub(t.now()); wait > 0 {
+		t.log.Debugf("rate limit of %s exhausted, waiting %s", resource, wait.Round(time.Second))
+		if err := t.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.pausedUntil[limit].Equal(until) {
+		delete(t.pausedUntil, limit)
+	}
+	return nil
+}
+
+// requestResource returns the rate limit resource a request is counted against.
+func requestResource(req *http.Request) string {
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		return "graphql"
+	}
+	return "core"
+}
+
+// rateLimitKey identifies the rate limit of the resource the request is counted against.
+// Every token has its own limits, so the key includes a hash of the credentials.
+func rateLimitKey(req *http.Request, resource string) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return hex.EncodeToString(sum[:8]) + "/" + resource
+}
+
+// rewindRequest returns the request for the given attempt, with a fresh body for retries.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("rewinding request body: %w", err)
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+// isRetryable reports whether the request can be sent again. GraphQL queries are sent
+// as POST requests, but only read data, unlike mutations.
+func isRetryable(req *http.Request) bool {
+	if isIdempotent(req.Method) {
+		return true
+	}
+	if requestResource(req) != "graphql" || req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	defer body.Close()
+	var payload struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return false
+	}
+	return payload.Query != "" && !strings.HasPrefix(strings.TrimSpace(payload.Query), "mutation")
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSecondaryRateLimit checks the body for the secondary rate limit message.
+// The body is restored, so it can still be read by the caller.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit"))
+}
+
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
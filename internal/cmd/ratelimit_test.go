@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katexochen/ghh/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type testResponse struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+
+	testCases := []struct {
+		method string
+		// path and body default to a REST request with "payload" as body.
+		path           string
+		body           string
+		responses      []testResponse
+		expectedStatus int
+		expectedCalls  int
+		expectedWaits  []time.Duration
+	}{
+		{ // success
+			method:         http.MethodGet,
+			responses:      []testResponse{{status: http.StatusOK}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+		},
+		{ // server error is retried for idempotent request
+			method: http.MethodDelete,
+			responses: []testResponse{
+				{status: http.StatusBadGateway},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusNoContent},
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedCalls:  3,
+			expectedWaits:  []time.Duration{time.Second, 2 * time.Second},
+		},
+		{ // server error is not retried for post
+			method:         http.MethodPost,
+			responses:      []testResponse{{status: http.StatusBadGateway}},
+			expectedStatus: http.StatusBadGateway,
+			expectedCalls:  1,
+		},
+		{ // server error with retry-after
+			method: http.MethodGet,
+			responses: []testResponse{
+				{status: http.StatusServiceUnavailable, headers: map[string]string{retryAfterHeader: "30"}},
+				{status: http.StatusOK},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{30 * time.Second},
+		},
+		{ // graphql query is retried
+			method: http.MethodPost,
+			path:   "/api/graphql",
+			body:   `{"query":"query($owner:String!){repositoryOwner(login:$owner){login}}"}`,
+			responses: []testResponse{
+				{status: http.StatusBadGateway},
+				{status: http.StatusOK},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{time.Second},
+		},
+		{ // graphql mutation is not retried
+			method:         http.MethodPost,
+			path:           "/api/graphql",
+			body:           `{"query":"mutation($input:AddProjectV2DraftIssueInput!){addProjectV2DraftIssue(input:$input){projectItem{id}}}"}`,
+			responses:      []testResponse{{status: http.StatusBadGateway}},
+			expectedStatus: http.StatusBadGateway,
+			expectedCalls:  1,
+		},
+		{ // server error retries are limited
+			method: http.MethodGet,
+			responses: []testResponse{
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  4,
+			expectedWaits:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{ // primary rate limit waits for reset
+			method: http.MethodPost,
+			responses: []testResponse{
+				{
+					status: http.StatusForbidden,
+					headers: map[string]string{
+						rateLimitRemainingHeader: "0",
+						rateLimitResetHeader:     reset,
+						rateLimitResourceHeader:  "core",
+					},
+				},
+				{status: http.StatusCreated},
+			},
+			expectedStatus: http.StatusCreated,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{31 * time.Second},
+		},
+		{ // secondary rate limit with retry-after
+			method: http.MethodPost,
+			responses: []testResponse{
+				{status: http.StatusTooManyRequests, headers: map[string]string{retryAfterHeader: "60"}},
+				{status: http.StatusOK},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{time.Minute},
+		},
+		{ // secondary rate limit backs off exponentially
+			method: http.MethodGet,
+			responses: []testResponse{
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit."}`},
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit."}`},
+				{status: http.StatusOK},
+			},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  3,
+			// The short backoff of server errors doesn't apply to secondary rate limits.
+			expectedWaits: []time.Duration{time.Minute, 2 * time.Minute},
+		},
+		{ // forbidden is returned
+			method:         http.MethodGet,
+			responses:      []testResponse{{status: http.StatusForbidden, body: `{"message": "Resource not accessible by integration"}`}},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  1,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			path, payload := "/repos/o/r", "payload"
+			if tc.path != "" {
+				path, payload = tc.path, tc.body
+			}
+
+			var calls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(err)
+				if r.Method == http.MethodPost {
+					assert.Equal(payload, string(body))
+				}
+				resp := tc.responses[calls]
+				calls++
+				for k, v := range resp.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(resp.status)
+				fmt.Fprint(w, resp.body)
+			}))
+			defer server.Close()
+
+			var waits []time.Duration
+			transport := newRateLimitTransport(http.DefaultTransport, &logger.DefaultLogger{})
+			transport.maxRetries = 3
+			transport.baseBackoff = time.Second
+			transport.now = func() time.Time { return now }
+			transport.sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				now = now.Add(d)
+				return nil
+			}
+			t.Cleanup(func() { now = time.Unix(1700000000, 0) })
+
+			req, err := http.NewRequestWithContext(context.Background(), tc.method, server.URL+path, strings.NewReader(payload))
+			require.NoError(err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(err)
+			defer resp.Body.Close()
+
+			assert.Equal(tc.expectedStatus, resp.StatusCode)
+			assert.Equal(tc.expectedCalls, calls)
+			assert.Equal(tc.expectedWaits, waits)
+			_, err = io.ReadAll(resp.Body)
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestRateLimitTransportPausesExhaustedResource(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	now := time.Unix(1700000000, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(rateLimitRemainingHeader, "0")
+		w.Header().Set(rateLimitResetHeader, strconv.FormatInt(now.Add(10*time.Second).Unix(), 10))
+		w.Header().Set(rateLimitResourceHeader, "core")
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	transport := newRateLimitTransport(http.DefaultTransport, &logger.DefaultLogger{})
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+		require.NoError(err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(err)
+		resp.Body.Close()
+	}
+
+	// The first request exhausted the limit, so the second one waits for the reset.
+	assert.Equal([]time.Duration{11 * time.Second}, waits)
+}
+
+func TestRateLimitTransportPausesPerToken(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	now := time.Unix(1700000000, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer exhausted" {
+			w.Header().Set(rateLimitRemainingHeader, "0")
+			w.Header().Set(rateLimitResetHeader, strconv.FormatInt(now.Add(10*time.Second).Unix(), 10))
+			w.Header().Set(rateLimitResourceHeader, "core")
+		}
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	transport := newRateLimitTransport(http.DefaultTransport, &logger.DefaultLogger{})
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	// The clients of different owners share the transport, each with its own token.
+	for _, token := range []string{"exhausted", "other", "exhausted"} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+		require.NoError(err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(err)
+		resp.Body.Close()
+	}
+
+	// Only the request with the exhausted token waits for the reset.
+	assert.Equal([]time.Duration{11 * time.Second}, waits)
+}
+
+func TestNewHTTPClientSharesTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	base := func(c *http.Client) http.RoundTripper {
+		return c.Transport.(*oauth2.Transport).Base
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+	log := newLogger(false)
+	a := newHTTPClient(&profile{Host: "shared.example.com"}, ts, log)
+	b := newHTTPClient(&profile{Host: "shared.example.com"}, ts, log)
+	other := newHTTPClient(&profile{Host: "other.example.com"}, ts, log)
+
+	assert.Same(base(a), base(b))
+	assert.NotSame(base(a), base(other))
+}
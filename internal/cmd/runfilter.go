@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+)
+
+// runFilter selects the workflow runs to delete. Filters supported by the API are
+// sent as list options, the others are applied to the listed runs.
+type runFilter struct {
+	status string
+	branch string
+	event  string
+	actor  string
+	// createdBefore selects runs created before the time, if set.
+	createdBefore time.Time
+	// keepLast is the number of newest runs that are kept.
+	keepLast int
+	// keepLastPerBranch is the number of newest runs per branch that are kept.
+	keepLastPerBranch int
+}
+
+// listOptions returns the filters supported by the API.
+func (f *runFilter) listOptions() *github.ListWorkflowRunsOptions {
+	opts := &github.ListWorkflowRunsOptions{
+		Status: f.status,
+		Branch: f.branch,
+		Event:  f.event,
+		Actor:  f.actor,
+	}
+	// The retention counts the newest runs regardless of their age,
+	// so the age can only be filtered by the API without retention rules.
+	if !f.createdBefore.IsZero() && !f.hasRetention() {
+		opts.Created = "<" + f.createdBefore.UTC().Format(time.RFC3339)
+	}
+	return opts
+}
+
+// hasRetention reports whether runs returned by the API can be kept by retention rules.
+func (f *runFilter) hasRetention() bool {
+	return f.keepLast > 0 || f.keepLastPerBranch > 0
+}
+
+// newRetention returns the retention state for one listing of the runs.
+func (f *runFilter) newRetention() *runRetention {
+	return &runRetention{
+		keepLast:          f.keepLast,
+		keepLastPerBranch: f.keepLastPerBranch,
+		perBranch:         map[string]int{},
+	}
+}
+
+// matches reports whether the run should be deleted. The runs of one listing
+// must be passed newest first, as returned by the API.
+func (f *runFilter) matches(run *github.WorkflowRun, retention *runRetention) bool {
+	if retention.keep(run) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && !run.GetCreatedAt().Before(f.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// runRetention keeps the newest runs overall and per branch.
+type runRetention struct {
+	keepLast          int
+	keepLastPerBranch int
+
+	seen      int
+	perBranch map[string]int
+}
+
+func (r *runRetention) keep(run *github.WorkflowRun) bool {
+	r.seen++
+	r.perBranch[run.GetHeadBranch()]++
+	return r.seen <= r.keepLast || r.perBranch[run.GetHeadBranch()] <= r.keepLastPerBranch
+}
+
+func addRunFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"status",
+		"",
+		"Only delete runs with the status or conclusion, e.g. 'completed', 'failure' or 'cancelled'",
+	)
+	cmd.Flags().String(
+		"branch",
+		"",
+		"Only delete runs of the branch",
+	)
+	cmd.Flags().String(
+		"event",
+		"",
+		"Only delete runs triggered by the event, e.g. 'push' or 'schedule'",
+	)
+	cmd.Flags().String(
+		"actor",
+		"",
+		"Only delete runs triggered by the user",
+	)
+	cmd.Flags().String(
+		"created-before",
+		"",
+		"Only delete runs created before the date, e.g. '2024-01-31' or '2024-01-31T12:00:00Z'",
+	)
+	cmd.Flags().String(
+		"older-than",
+		"",
+		"Only delete runs older than the age, e.g. '30d', '2w' or '12h'",
+	)
+	cmd.Flags().Int(
+		"keep-last",
+		0,
+		"Always keep the newest N runs; age filters apply to the rest",
+	)
+	cmd.Flags().Int(
+		"keep-last-per-branch",
+		0,
+		"Always keep the newest N runs of every branch; age filters apply to the rest",
+	)
+}
+
+func parseRunFilterFlags(cmd *cobra.Command, now time.Time) (*runFilter, error) {
+	f := &runFilter{}
+
+	var err error
+	f.status, err = cmd.Flags().GetString("status")
+	if err != nil {
+		return nil, err
+	}
+	f.branch, err = cmd.Flags().GetString("branch")
+	if err != nil {
+		return nil, err
+	}
+	f.event, err = cmd.Flags().GetString("event")
+	if err != nil {
+		return nil, err
+	}
+	f.actor, err = cmd.Flags().GetString("actor")
+	if err != nil {
+		return nil, err
+	}
+
+	createdBefore, err := cmd.Flags().GetString("created-before")
+	if err != nil {
+		return nil, err
+	}
+	if createdBefore != "" {
+		f.createdBefore, err = parseDate(createdBefore)
+		if err != nil {
+			return nil, fmt.Errorf("parsing '--created-before': %w", err)
+		}
+	}
+	olderThan, err := cmd.Flags().GetString("older-than")
+	if err != nil {
+		return nil, err
+	}
+	if olderThan != "" {
+		age, err := parseAge(olderThan)
+		if err != nil {
+			return nil, fmt.Errorf("parsing '--older-than': %w", err)
+		}
+		// If both are set, the earlier cutoff wins, so both conditions hold.
+		if cutoff := now.Add(-age); f.createdBefore.IsZero() || cutoff.Before(f.createdBefore) {
+			f.createdBefore = cutoff
+		}
+	}
+
+	f.keepLast, err = cmd.Flags().GetInt("keep-last")
+	if err != nil {
+		return nil, err
+	}
+	f.keepLastPerBranch, err = cmd.Flags().GetInt("keep-last-per-branch")
+	if err != nil {
+		return nil, err
+	}
+	if f.keepLast < 0 || f.keepLastPerBranch < 0 {
+		return nil, errors.New("'--keep-last' and '--keep-last-per-branch' must not be negative")
+	}
+
+	return f, nil
+}
+
+// parseAge parses a duration like time.ParseDuration, with the additional
+// units 'd' for days and 'w' for weeks, e.g. '30d' or '2w'.
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return age, nil
+}
+
+// parseDate parses a date like '2024-01-31' or a RFC 3339 timestamp.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAge(t *testing.T) {
+	testCases := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{age: "30d", want: 30 * 24 * time.Hour},              // days
+		{age: "2w", want: 14 * 24 * time.Hour},               // weeks
+		{age: "12h30m", want: 12*time.Hour + 30*time.Minute}, // go duration
+		{age: "1.5d", wantErr: true},                         // fractional day
+		{age: "-1h", wantErr: true},                          // negative
+		{age: "30", wantErr: true},                           // no unit
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			age, err := parseAge(tc.age)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, age)
+		})
+	}
+}
+
+func TestRunFilterMatches(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	run := func(id int64, branch string, age time.Duration) *github.WorkflowRun {
+		return &github.WorkflowRun{
+			ID:         toPtr(id),
+			HeadBranch: toPtr(branch),
+			CreatedAt:  &github.Timestamp{Time: now.Add(-age)},
+		}
+	}
+	// Newest first, as listed by the API.
+	runs := []*github.WorkflowRun{
+		run(1, "main", time.Hour),
+		run(2, "feat", 2*time.Hour),
+		run(3, "main", 3*24*time.Hour),
+		run(4, "main", 4*24*time.Hour),
+		run(5, "feat", 5*24*time.Hour),
+		run(6, "main", 6*24*time.Hour),
+	}
+
+	testCases := []struct {
+		filter  runFilter
+		wantIDs []int64
+	}{
+		{ // no filter
+			wantIDs: []int64{1, 2, 3, 4, 5, 6},
+		},
+		{ // keep last
+			filter:  runFilter{keepLast: 2},
+			wantIDs: []int64{3, 4, 5, 6},
+		},
+		{ // keep last per branch
+			filter:  runFilter{keepLastPerBranch: 1},
+			wantIDs: []int64{3, 4, 5, 6},
+		},
+		{ // keep last and per branch
+			filter:  runFilter{keepLast: 1, keepLastPerBranch: 2},
+			wantIDs: []int64{4, 6},
+		},
+		{ // created before
+			filter:  runFilter{createdBefore: now.Add(-2 * 24 * time.Hour)},
+			wantIDs: []int64{3, 4, 5, 6},
+		},
+		{ // created before with retention
+			filter:  runFilter{createdBefore: now.Add(-2 * 24 * time.Hour), keepLast: 4},
+			wantIDs: []int64{5, 6},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			retention := tc.filter.newRetention()
+			var ids []int64
+			for _, r := range runs {
+				if tc.filter.matches(r, retention) {
+					ids = append(ids, r.GetID())
+				}
+			}
+			assert.Equal(tc.wantIDs, ids)
+		})
+	}
+}
+
+func TestRunFilterListOptions(t *testing.T) {
+	assert := assert.New(t)
+	createdBefore := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	f := runFilter{status: "failure", branch: "main", createdBefore: createdBefore}
+	opts := f.listOptions()
+	assert.Equal("failure", opts.Status)
+	assert.Equal("main", opts.Branch)
+	assert.Equal("<2024-01-31T00:00:00Z", opts.Created)
+
+	// With retention rules, the age is filtered on the listed runs.
+	f.keepLast = 10
+	assert.Empty(f.listOptions().Created)
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/spf13/cobra"
+)
+
+const (
+	archiveIndexJSON = "index.json"
+	archiveIndexHTML = "index.html"
+)
+
+// NewRunsCmd creates a new command group for archived workflow runs.
+func NewRunsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Work with workflow runs archived by delete-all-runs",
+	}
+	cmd.AddCommand(
+		newRunsRestoreIndexCmd(),
+	)
+	return cmd
+}
+
+func newRunsRestoreIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore-index <archive>",
+		Short: "Build a browsable index of a run archive",
+		Long: `
+Build an index of the runs in an archive written by 'delete-all-runs --archive'.
+
+The index is written as index.html and index.json. For directory archives, the
+index is written into the archive and links the archived files. For tar.gz
+archives, it is written next to the archive file.
+		`,
+		Args: cobra.ExactArgs(1),
+		RunE: restoreRunsIndex,
+	}
+	cmd.Flags().String(
+		"output",
+		"",
+		"Directory to write the index to. Defaults to the archive directory, or the directory of a tar.gz archive.",
+	)
+	return cmd
+}
+
+func restoreRunsIndex(cmd *cobra.Command, args []string) error {
+	archivePath := args[0]
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = archivePath
+		if isTarArchive(archivePath) {
+			output = filepath.Dir(archivePath)
+		}
+	}
+
+	runs, err := readArchivedRuns(archivePath)
+	if err != nil {
+		return err
+	}
+
+	// Files of directory archives can be linked relative to the index.
+	var linkBase string
+	if !isTarArchive(archivePath) {
+		rel, err := filepath.Rel(output, archivePath)
+		if err != nil {
+			return err
+		}
+		linkBase = filepath.ToSlash(rel)
+	}
+
+	if err := os.MkdirAll(output, 0o755); err != nil {
+		return err
+	}
+	if err := writeIndexJSON(filepath.Join(output, archiveIndexJSON), runs); err != nil {
+		return err
+	}
+	if err := writeIndexHTML(filepath.Join(output, archiveIndexHTML), archivePath, linkBase, runs); err != nil {
+		return err
+	}
+
+	fmt.Printf("Indexed %d runs, wrote %s and %s to %s.\n", len(runs), archiveIndexHTML, archiveIndexJSON, output)
+	return nil
+}
+
+// archivedRun is an entry of the archive index.
+type archivedRun struct {
+	Repository string    `json:"repository"`
+	ID         int64     `json:"id"`
+	Workflow   string    `json:"workflow"`
+	Title      string    `json:"title"`
+	Branch     string    `json:"branch"`
+	Event      string    `json:"event"`
+	Status     string    `json:"status"`
+	Actor      string    `json:"actor"`
+	CreatedAt  time.Time `json:"created_at"`
+	URL        string    `json:"url"`
+	// Files are the slash-separated paths of the archived files, relative to the archive.
+	Files []string `json:"files"`
+}
+
+// readArchivedRuns returns the complete runs of the archive, by repository and newest first.
+func readArchivedRuns(archivePath string) ([]archivedRun, error) {
+	runs := map[string]*archivedRun{}
+	files := map[string][]string{}
+	err := walkArchive(archivePath, func(name string, read func() ([]byte, error)) error {
+		dir := path.Dir(name)
+		// Runs are stored in <owner>/<repo>/<run ID>.
+		if strings.Count(dir, "/") != 2 {
+			return nil
+		}
+		files[dir] = append(files[dir], name)
+		if path.Base(name) != archiveRunFile {
+			return nil
+		}
+
+		data, err := read()
+		if err != nil {
+			return err
+		}
+		var run github.WorkflowRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			return fmt.Errorf("parsing %s: %w", name, err)
+		}
+		runs[dir] = &archivedRun{
+			Repository: path.Dir(dir),
+			ID:         run.GetID(),
+			Workflow:   run.GetName(),
+			Title:      run.GetDisplayTitle(),
+			Branch:     run.GetHeadBranch(),
+			Event:      run.GetEvent(),
+			Status:     runStatus(&run),
+			Actor:      run.GetActor().GetLogin(),
+			CreatedAt:  run.GetCreatedAt().Time,
+			URL:        run.GetHTMLURL(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := make([]archivedRun, 0, len(runs))
+	for dir, run := range runs {
+		run.Files = files[dir]
+		sort.Strings(run.Files)
+		indexed = append(indexed, *run)
+	}
+	sort.Slice(indexed, func(i, j int) bool {
+		if indexed[i].Repository != indexed[j].Repository {
+			return indexed[i].Repository < indexed[j].Repository
+		}
+		return indexed[i].CreatedAt.After(indexed[j].CreatedAt)
+	})
+	return indexed, nil
+}
+
+func writeIndexJSON(name string, runs []archivedRun) error {
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+var indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"base": path.Base,
+	"link": path.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Archived workflow runs</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Archived workflow runs</h1>
+<p>{{len .Runs}} runs archived in {{.Archive}}.</p>
+<table>
+<tr><th>Repository</th><th>Run</th><th>Workflow</th><th>Title</th><th>Branch</th><th>Event</th><th>Status</th><th>Actor</th><th>Created</th><th>Files</th></tr>
+{{- range .Runs}}
+<tr>
+<td>{{.Repository}}</td>
+<td>{{if .URL}}<a href="{{.URL}}">{{.ID}}</a>{{else}}{{.ID}}{{end}}</td>
+<td>{{.Workflow}}</td>
+<td>{{.Title}}</td>
+<td>{{.Branch}}</td>
+<td>{{.Event}}</td>
+<td>{{.Status}}</td>
+<td>{{.Actor}}</td>
+<td>{{.CreatedAt.Format "2006-01-02 15:04:05"}}</td>
+<td>{{range .Files}}{{if $.Links}}<a href="{{link $.LinkBase .}}">{{base .}}</a>{{else}}{{.}}{{end}} {{end}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+func writeIndexHTML(name, archivePath, linkBase string, runs []archivedRun) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = indexTemplate.Execute(file, struct {
+		Archive  string
+		Links    bool
+		LinkBase string
+		Runs     []archivedRun
+	}{
+		Archive:  archivePath,
+		Links:    linkBase != "",
+		LinkBase: linkBase,
+		Runs:     runs,
+	})
+	return errors.Join(err, file.Close())
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -22,47 +21,189 @@ func NewSetAuthCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "set-auth",
 		Short: "Set the GitHub personal access token",
-		RunE:  setupAuth,
+		Long: `
+Store the GitHub personal access token in a credential store.
+
+The token is saved for the profile selected with '--profile' or '--host'. A profile
+that doesn't exist yet is created, the first profile becomes the default.
+
+With '--app-id' and '--app-private-key', the profile authenticates as GitHub App. No token
+is stored then, installation tokens are created on demand.
+
+Per default, the freedesktop Secret Service is used if available. Otherwise, the token
+is written to a file encrypted with a passphrase, which is read from GHH_PASSPHRASE or
+from the terminal. Storing the token unencrypted requires '--insecure-storage'.
+		`,
+		RunE: setupAuth,
 	}
+	cmd.Flags().String(
+		"store",
+		storeAuto,
+		fmt.Sprintf("Credential store to use, one of %q, %q, %q", storeAuto, storeSecretService, storeEncryptedFile),
+	)
+	cmd.Flags().Bool(
+		"insecure-storage",
+		false,
+		"Store the token unencrypted in the settings file",
+	)
+	cmd.Flags().String(
+		"api-url",
+		"",
+		"Base URL of the REST API. Derived from the host if empty.",
+	)
+	cmd.Flags().String(
+		"graphql-url",
+		"",
+		"URL of the GraphQL API. Derived from the host if empty.",
+	)
+	cmd.Flags().Int64(
+		"app-id",
+		0,
+		"Authenticate as the GitHub App with this ID instead of with a token",
+	)
+	cmd.Flags().String(
+		"app-private-key",
+		"",
+		"Path to the PEM private key of the GitHub App",
+	)
+	cmd.Flags().String(
+		"app-installation-owner",
+		"",
+		"Account the GitHub App is installed on. Taken from the target of the command if empty.",
+	)
+	cmd.MarkFlagsRequiredTogether("app-id", "app-private-key")
 	return cmd
 }
 
-func setupAuth(_ *cobra.Command, _ []string) error {
-	var token string
+func setupAuth(cmd *cobra.Command, _ []string) error {
+	flags, err := parseSetAuthFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	p, err := lookupProfile(cmd, true, "")
+	if err != nil {
+		return err
+	}
+	if flags.apiURL != "" {
+		p.APIURL = flags.apiURL
+	}
+	if flags.graphQLURL != "" {
+		p.GraphQLURL = flags.graphQLURL
+	}
+
+	if flags.appID != 0 {
+		return setupAppAuth(p, flags)
+	}
+
+	store, err := selectCredentialStore(flags.store, flags.insecureStorage)
+	if err != nil {
+		return err
+	}
 
-	token = os.Getenv(tokenEnvVar)
+	token := os.Getenv(tokenEnvVar)
 	if token == "" {
-		var err error
 		token, err = readTokenFromUserInput()
 		if err != nil {
 			return err
 		}
 	}
 
-	configDir, err := os.UserConfigDir()
+	fmt.Println("Validating token...")
+	info, err := inspectToken(cmd.Context(), p, token, newLogger(false))
 	if err != nil {
+		return fmt.Errorf("validating token: %w", err)
+	}
+	fmt.Printf("Token is valid for user %s.\n", info.login)
+
+	if err := storeToken(p, store, token); err != nil {
 		return err
 	}
 
-	settings := settings{Token: token}
+	fmt.Printf("Successfully saved token for profile %q (%s) to %s store.\n", p.Name(), p.Host, store.Name())
+	return nil
+}
 
-	file, err := json.Marshal(settings)
+func setupAppAuth(p *profile, flags *setAuthFlags) error {
+	keyPath, err := filepath.Abs(flags.appPrivateKey)
 	if err != nil {
 		return err
 	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("reading app private key: %w", err)
+	}
+	if _, err := parseRSAPrivateKey(keyPEM); err != nil {
+		return fmt.Errorf("parsing app private key: %w", err)
+	}
 
-	if err := os.MkdirAll(filepath.Join(configDir, "ghh"), 0o755); err != nil {
+	settings, err := loadSettings()
+	if err != nil {
 		return err
 	}
-
-	if err := os.WriteFile(filepath.Join(configDir, configFilePath), file, 0o600); err != nil {
+	p.AppID = flags.appID
+	p.AppPrivateKeyPath = keyPath
+	p.AppInstallationOwner = flags.appInstallationOwner
+	settings.Profiles[p.Name()] = p
+	if settings.DefaultProfile == "" {
+		settings.DefaultProfile = p.Name()
+	}
+	if err := settings.save(); err != nil {
 		return err
 	}
 
-	fmt.Println("Successfully saved token.")
+	fmt.Printf("Successfully configured profile %q (%s) to authenticate as GitHub App %d.\n", p.Name(), p.Host, p.AppID)
 	return nil
 }
 
+func selectCredentialStore(name string, insecureStorage bool) (credentialStore, error) {
+	if insecureStorage {
+		if name != storeAuto {
+			return nil, errors.New("'--store' and '--insecure-storage' are mutually exclusive")
+		}
+		return &plaintextStore{}, nil
+	}
+	if name == storeAuto {
+		return bestCredentialStore(), nil
+	}
+	if name == storePlaintext {
+		return nil, errors.New("use '--insecure-storage' to store the token unencrypted")
+	}
+	store, err := newCredentialStore(name)
+	if err != nil {
+		return nil, err
+	}
+	if !store.Available() {
+		return nil, fmt.Errorf("credential store %q is not available on this system", name)
+	}
+	return store, nil
+}
+
+// storeToken saves the profile in the settings and its token in the given store.
+// The first profile saved becomes the default profile. The token is stored before the
+// settings refer to the store, so a failing store leaves the previous token in place.
+func storeToken(p *profile, store credentialStore, token string) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	if store.Name() == storePlaintext {
+		p.Token = token
+	} else {
+		if err := store.Set(p.Name(), token); err != nil {
+			return fmt.Errorf("saving token to %s store: %w", store.Name(), err)
+		}
+		// Don't leave a previously stored plaintext token behind.
+		p.Token = ""
+	}
+	p.CredentialStore = store.Name()
+	settings.Profiles[p.Name()] = p
+	if settings.DefaultProfile == "" {
+		settings.DefaultProfile = p.Name()
+	}
+	return settings.save()
+}
+
 func readTokenFromUserInput() (string, error) {
 	fmt.Println("Please enter your GitHub personal access token or restart -set-auth with the GHH_TOKEN environment variable set.")
 	fmt.Print("Token: ")
@@ -74,32 +215,49 @@ func readTokenFromUserInput() (string, error) {
 	return string(byteToken), nil
 }
 
-type settings struct {
-	Token string `json:"token"`
+type setAuthFlags struct {
+	store           string
+	insecureStorage bool
+	apiURL          string
+	graphQLURL      string
+
+	appID                int64
+	appPrivateKey        string
+	appInstallationOwner string
 }
 
-func getToken() (string, error) {
-	token := os.Getenv(tokenEnvVar)
-	if token != "" {
-		return token, nil
-	}
+func parseSetAuthFlags(cmd *cobra.Command) (*setAuthFlags, error) {
+	flags := &setAuthFlags{}
 
-	configDir, err := os.UserConfigDir()
+	var err error
+	flags.store, err = cmd.Flags().GetString("store")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	file, err := os.ReadFile(filepath.Join(configDir, configFilePath))
-	if errors.Is(err, os.ErrNotExist) {
-		return "", errors.New("no token found. Please set the GHH_TOKEN environment variable or run `ghh setauth`")
-	} else if err != nil {
-		return "", err
+	flags.insecureStorage, err = cmd.Flags().GetBool("insecure-storage")
+	if err != nil {
+		return nil, err
 	}
-
-	var settings settings
-	if err := json.Unmarshal(file, &settings); err != nil {
-		return "", err
+	flags.apiURL, err = cmd.Flags().GetString("api-url")
+	if err != nil {
+		return nil, err
+	}
+	flags.graphQLURL, err = cmd.Flags().GetString("graphql-url")
+	if err != nil {
+		return nil, err
+	}
+	flags.appID, err = cmd.Flags().GetInt64("app-id")
+	if err != nil {
+		return nil, err
+	}
+	flags.appPrivateKey, err = cmd.Flags().GetString("app-private-key")
+	if err != nil {
+		return nil, err
+	}
+	flags.appInstallationOwner, err = cmd.Flags().GetString("app-installation-owner")
+	if err != nil {
+		return nil, err
 	}
 
-	return settings.Token, nil
+	return flags, nil
 }
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreToken(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	require.NoError(storeToken(&profile{name: defaultHost, Host: defaultHost}, &plaintextStore{}, "ghp_old"))
+	token, err := (&plaintextStore{}).Get(defaultHost)
+	require.NoError(err)
+	assert.Equal("ghp_old", token)
+
+	// A failing store must not drop the token that is already stored.
+	err = storeToken(&profile{name: defaultHost, Host: defaultHost}, &failingStore{}, "ghp_new")
+	assert.Error(err)
+	settings, err := loadSettings()
+	require.NoError(err)
+	assert.Equal(storePlaintext, settings.Profiles[defaultHost].CredentialStore)
+	assert.Equal("ghp_old", settings.Profiles[defaultHost].Token)
+}
+
+// failingStore is a credential store that can't save tokens.
+type failingStore struct{}
+
+func (s *failingStore) Name() string               { return "failing" }
+func (s *failingStore) Available() bool            { return true }
+func (s *failingStore) Get(string) (string, error) { return "", ErrCredentialNotFound }
+func (s *failingStore) Set(string, string) error   { return errors.New("store is locked") }
+func (s *failingStore) Delete(string) error        { return nil }
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	hostEnvVar    = "GHH_HOST"
+	profileEnvVar = "GHH_PROFILE"
+
+	defaultHost = "github.com"
+)
+
+type settings struct {
+	DefaultProfile string              `json:"defaultProfile,omitempty"`
+	Profiles       map[string]*profile `json:"profiles,omitempty"`
+	// Commands holds default flag values per command, see ApplyDefaults.
+	Commands map[string]map[string]any `json:"commands,omitempty"`
+
+	// Token and CredentialStore are the single-token settings of older versions.
+	// They are migrated to a github.com profile on load.
+	Token           string `json:"token,omitempty"`
+	CredentialStore string `json:"credentialStore,omitempty"`
+}
+
+// profile holds the authentication and endpoints for one GitHub host.
+type profile struct {
+	// name is the key of the profile in the settings.
+	name string
+	// tokenSource forces the token to be read from a single source, see tokenResolvers.
+	tokenSource string
+
+	Host       string `json:"host"`
+	APIURL     string `json:"apiURL,omitempty"`
+	GraphQLURL string `json:"graphqlURL,omitempty"`
+
+	CredentialStore string `json:"credentialStore,omitempty"`
+	// Token is only set when the plaintext store is used.
+	Token string `json:"token,omitempty"`
+
+	// AppID, AppPrivateKeyPath and AppInstallationOwner configure authentication
+	// as GitHub App instead of with a token.
+	AppID                int64  `json:"appID,omitempty"`
+	AppPrivateKeyPath    string `json:"appPrivateKeyPath,omitempty"`
+	AppInstallationOwner string `json:"appInstallationOwner,omitempty"`
+}
+
+func newProfile(name, host string) *profile {
+	return &profile{name: name, Host: host}
+}
+
+// Name returns the name of the profile, which is also the account used in credential stores.
+func (p *profile) Name() string {
+	return p.name
+}
+
+// IsEnterprise reports whether the profile targets a GitHub Enterprise Server instance.
+func (p *profile) IsEnterprise() bool {
+	return !strings.EqualFold(p.Host, defaultHost)
+}
+
+// RESTURL returns the base URL of the REST API.
+func (p *profile) RESTURL() string {
+	if p.APIURL != "" {
+		return p.APIURL
+	}
+	if !p.IsEnterprise() {
+		return "https://api.github.com/"
+	}
+	return fmt.Sprintf("https://%s/api/v3/", p.Host)
+}
+
+// UploadURL returns the base URL for uploads.
+func (p *profile) UploadURL() string {
+	if !p.IsEnterprise() {
+		return "https://uploads.github.com/"
+	}
+	return fmt.Sprintf("https://%s/api/uploads/", p.Host)
+}
+
+// GraphQLEndpoint returns the URL of the GraphQL API.
+func (p *profile) GraphQLEndpoint() string {
+	if p.GraphQLURL != "" {
+		return p.GraphQLURL
+	}
+	if !p.IsEnterprise() {
+		return "https://api.github.com/graphql"
+	}
+	return fmt.Sprintf("https://%s/api/graphql", p.Host)
+}
+
+func settingsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, configFilePath), nil
+}
+
+// loadSettings reads the settings file. A missing file results in empty settings.
+func loadSettings() (*settings, error) {
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &settings{}
+	file, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.Profiles = map[string]*profile{}
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if s.Profiles == nil {
+		s.Profiles = map[string]*profile{}
+	}
+	for name, p := range s.Profiles {
+		if p == nil {
+			return nil, fmt.Errorf("parsing %s: profile %q is empty", path, name)
+		}
+		p.name = name
+		if p.Host == "" {
+			p.Host = defaultHost
+		}
+	}
+	s.migrate()
+	return s, nil
+}
+
+// migrate moves the single token of older settings into a github.com profile.
+func (s *settings) migrate() {
+	if s.Token == "" && s.CredentialStore == "" {
+		return
+	}
+	if _, ok := s.Profiles[defaultHost]; !ok {
+		p := newProfile(defaultHost, defaultHost)
+		p.Token = s.Token
+		p.CredentialStore = s.CredentialStore
+		if p.CredentialStore == "" {
+			p.CredentialStore = storePlaintext
+		}
+		s.Profiles[defaultHost] = p
+	}
+	if s.DefaultProfile == "" {
+		s.DefaultProfile = defaultHost
+	}
+	s.Token = ""
+	s.CredentialStore = ""
+}
+
+func (s *settings) save() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+
+	file, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, file, 0o600)
+}
+
+// profileByHost returns the profile for the given host. If several profiles target
+// the host, the one named after the host is preferred.
+func (s *settings) profileByHost(host string) (*profile, error) {
+	if p, ok := s.Profiles[host]; ok && strings.EqualFold(p.Host, host) {
+		return p, nil
+	}
+	var matches []string
+	for name, p := range s.Profiles {
+		if strings.EqualFold(p.Host, host) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return s.Profiles[matches[0]], nil
+	default:
+		sort.Strings(matches)
+		return nil, fmt.Errorf("multiple profiles for host %s (%s), select one with --profile",
+			host, strings.Join(matches, ", "))
+	}
+}
+
+// resolveProfile determines the profile a command should use. The profile is selected
+// by --profile or GHH_PROFILE, then by --host or GHH_HOST, then by the default profile
+// of the settings. If no profile exists for a host, a profile without stored
+// credentials is returned, so the token can still be passed via GHH_TOKEN.
+func resolveProfile(cmd *cobra.Command) (*profile, error) {
+	return lookupProfile(cmd, false, "")
+}
+
+// resolveProfileForRepo resolves the profile like resolveProfile for a command working
+// on a repository of repoHost. Unless a profile or host is selected explicitly, the
+// default profile is only used if it targets repoHost, otherwise the profile of repoHost.
+func resolveProfileForRepo(cmd *cobra.Command, repoHost string) (*profile, error) {
+	return lookupProfile(cmd, false, repoHost)
+}
+
+// lookupProfile resolves the profile like resolveProfile. If create is set, a profile
+// selected by name that doesn't exist yet is created for the selected host.
+func lookupProfile(cmd *cobra.Command, create bool, repoHost string) (*profile, error) {
+	p, err := lookupProfileFromSettings(cmd, create, repoHost)
+	if err != nil {
+		return nil, err
+	}
+	p.tokenSource, err = cmd.Flags().GetString("token-source")
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func lookupProfileFromSettings(cmd *cobra.Command, create bool, repoHost string) (*profile, error) {
+	settings, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	profileName, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return nil, err
+	}
+	if profileName == "" {
+		profileName = os.Getenv(profileEnvVar)
+	}
+	host, err := cmd.Flags().GetString("host")
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = os.Getenv(hostEnvVar)
+	}
+
+	if profileName != "" {
+		p, ok := settings.Profiles[profileName]
+		if ok {
+			return p, nil
+		}
+		if !create {
+			return nil, fmt.Errorf("profile %q not found", profileName)
+		}
+		if host == "" {
+			host = defaultHost
+		}
+		return newProfile(profileName, host), nil
+	}
+
+	if host == "" {
+		p, ok := settings.Profiles[settings.DefaultProfile]
+		if ok && (repoHost == "" || strings.EqualFold(p.Host, repoHost)) {
+			return p, nil
+		}
+		host = repoHost
+	}
+	if host == "" {
+		host = defaultHost
+	}
+
+	p, err := settings.profileByHost(host)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = newProfile(host, host)
+	}
+	return p, nil
+}
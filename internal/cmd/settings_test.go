@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileEndpoints(t *testing.T) {
+	testCases := []struct {
+		profile         *profile
+		expectedREST    string
+		expectedGraphQL string
+	}{
+		{
+			profile:         newProfile("github.com", "github.com"),
+			expectedREST:    "https://api.github.com/",
+			expectedGraphQL: "https://api.github.com/graphql",
+		},
+		{
+			profile:         newProfile("work", "ghe.example.com"),
+			expectedREST:    "https://ghe.example.com/api/v3/",
+			expectedGraphQL: "https://ghe.example.com/api/graphql",
+		},
+		{
+			profile: &profile{
+				Host:       "ghe.example.com",
+				APIURL:     "https://api.ghe.example.com/",
+				GraphQLURL: "https://api.ghe.example.com/graphql",
+			},
+			expectedREST:    "https://api.ghe.example.com/",
+			expectedGraphQL: "https://api.ghe.example.com/graphql",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tc.expectedREST, tc.profile.RESTURL())
+			assert.Equal(tc.expectedGraphQL, tc.profile.GraphQLEndpoint())
+		})
+	}
+}
+
+func TestSettingsMigrate(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &settings{
+		Token:    "ghp_legacy",
+		Profiles: map[string]*profile{},
+	}
+	s.migrate()
+
+	assert.Empty(s.Token)
+	assert.Equal(defaultHost, s.DefaultProfile)
+	assert.Equal(storePlaintext, s.Profiles[defaultHost].CredentialStore)
+	assert.Equal("ghp_legacy", s.Profiles[defaultHost].Token)
+}
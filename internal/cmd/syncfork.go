@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v61/github"
-	"github.com/katexochen/ghh/internal/logger"
+	"github.com/katexochen/ghh/internal/selector"
 	"github.com/spf13/cobra"
 )
 
@@ -20,9 +22,14 @@ func NewSyncForksCmd() *cobra.Command {
 This command will sync all forks of a user with their upstream repository. It will
 fast-forward the fork if possible, otherwise it will merge the upstream branch.
 
+The forks of organizations and other users are synced with '--owner'.
+
 Per default, the target of the merge is the default branch of the fork.
 		`,
 		RunE: syncForks,
+		Annotations: map[string]string{
+			requiredPermissionsAnnotation: "contents:write,workflows:write",
+		},
 	}
 
 	cmd.Flags().StringSliceP(
@@ -37,6 +44,46 @@ Per default, the target of the merge is the default branch of the fork.
 		[]string{},
 		"Repositories to ignore.",
 	)
+	cmd.Flags().StringSlice(
+		"branch-map",
+		nil,
+		"Sync every mapped branch of the fork with an upstream branch, e.g. "+
+			"'release-1.2=upstream:release-1.2,main=upstream:master'. Overrides '--target-branches'.",
+	)
+	cmd.Flags().String(
+		"forks-file",
+		defaultForksConfigPath,
+		"Path of the per-fork configuration, used if it exists",
+	)
+	cmd.Flags().Bool(
+		"ignore-schedule",
+		false,
+		"Sync all forks regardless of their schedule in the forks file",
+	)
+	cmd.Flags().StringSlice(
+		"affiliation",
+		[]string{"owner"},
+		"Sync the forks the user has access to with the affiliation, any of owner, collaborator, "+
+			"organization_member. Forks of other owners are synced with '--owner'.",
+	)
+	cmd.Flags().Bool(
+		"report",
+		false,
+		"Print how many commits every fork is ahead and behind its upstream and how it would be "+
+			"synced, without syncing",
+	)
+	cmd.Flags().Int(
+		"parallel",
+		4,
+		"Number of forks synced in parallel",
+	)
+	cmd.Flags().BoolP(
+		"yes",
+		"y",
+		false,
+		"Don't ask for confirmation",
+	)
+	selector.AddFlags(cmd.Flags())
 	cmd.Flags().Bool(
 		"dont-target-default",
 		false,
@@ -53,24 +100,40 @@ func syncForks(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	var log loggerI
-	if flags.verbose {
-		log = &logger.VerboseLogger{}
-	} else {
-		log = &logger.DefaultLogger{}
+	log := newLogger(flags.verbose)
+
+	config, err := loadForksConfig(flags.forksFile, cmd.Flags().Changed("forks-file"))
+	if err != nil {
+		return err
 	}
 
-	token, err := getToken()
+	p, err := resolveProfile(cmd)
 	if err != nil {
-		return fmt.Errorf("getting token: %w", err)
+		return err
 	}
 
-	c := newGithubClient(cmd.Context(), "", "", token) // TODO: refactor client to not have owner and repo
+	statePath, err := defaultSyncStatePath(p.Host)
+	if err != nil {
+		return err
+	}
+	state, err := loadSyncState(statePath)
+	if err != nil {
+		return err
+	}
 
-	log.Debugf("listing forks")
-	forks, err := c.GetUserForks(cmd.Context())
+	// A GitHub App is installed per owner and can't list the forks of a user.
+	app, err := loadAppConfig(p)
 	if err != nil {
-		return fmt.Errorf("listing forks: %w", err)
+		return err
+	}
+	if app != nil && len(flags.selector.Owners) == 0 {
+		return errors.New("syncing forks with a GitHub App requires selecting the owners with '--owner'")
+	}
+	clients := newOwnerClients(cmd, p, log, flags.dryRun)
+
+	forks, err := listForks(cmd.Context(), clients, flags, log)
+	if err != nil {
+		return err
 	}
 
 	for _, fork := range forks {
@@ -79,80 +142,594 @@ func syncForks(cmd *cobra.Command, _ []string) error {
 	log.Debugf("%d forks found", len(forks))
 
 	forks = filterIgnoredRepos(forks, flags.ignoreRepos)
+	forks = flags.selector.Filter(forks)
+	// The schedule only limits syncing, the report shows all forks.
+	forks = filterConfiguredForks(forks, config, state, flags.ignoreSchedule || flags.report, time.Now(), log)
 	log.Debugf("%d remaining after filtering", len(forks))
 
-	var retErr error
-	var fastforwarded, merged, notbehind, skipped, failed int
-	for _, fork := range forks {
-		var branch string
-		if !flags.dontTargetDefault {
-			branch = fork.GetDefaultBranch()
-			log.Debugf("%s: default branch is %s", fork.GetFullName(), branch)
-		}
-
-		for _, targetBranch := range flags.targetBranches {
-			log.Debugf("%s: checking if branch %q exists", fork.GetFullName(), targetBranch)
-			if _, err := c.GetBranch(cmd.Context(), fork, targetBranch); err == nil {
-				branch = targetBranch
-				log.Debugf("%s: using target branch %q", fork.GetFullName(), branch)
-				break
-			}
+	// Each fork is synced with the client of the owner it was listed for.
+	forkOwners := make([]string, len(forks))
+	var owners []string
+	repos := map[string][]repoRef{}
+	for i, fork := range forks {
+		if len(flags.selector.Owners) > 0 {
+			forkOwners[i] = fork.GetOwner().GetLogin()
 		}
+		key := strings.ToLower(forkOwners[i])
+		if _, ok := repos[key]; !ok {
+			owners = append(owners, forkOwners[i])
+		}
+		repos[key] = append(repos[key], repoRef{host: p.Host, owner: fork.GetOwner().GetLogin(), name: fork.GetName()})
+	}
 
-		if branch == "" {
-			log.Warnf("%s: no target branch found, skipping", fork.GetFullName())
-			skipped++
-			continue
+	// The report doesn't change anything, so it doesn't need write access.
+	if !flags.report {
+		for _, owner := range owners {
+			_, ts, err := clients.forOwner(cmd.Context(), owner)
+			if err != nil {
+				return err
+			}
+			if err := preflight(cmd.Context(), cmd, p, ts, log, repos[strings.ToLower(owner)]...); err != nil {
+				return err
+			}
 		}
+	}
 
-		log.Infof("%s: syncing fork branch %q with upstream", fork.GetFullName(), branch)
-		result, err := c.SyncFork(cmd.Context(), fork, branch)
-		if errors.Is(err, context.Canceled) {
+	syncer := &forkSyncer{
+		flags:   flags,
+		config:  config,
+		logs:    newLogGroups(log),
+		results: make([]forkSyncResult, len(forks)),
+	}
+	// Syncing doesn't discard changes, so unlike deleting, it doesn't require '--yes' in
+	// scripts and workflows without a terminal.
+	if !flags.report && !flags.dryRun && !flags.yes && stdinIsTerminal() && len(forks) > 0 {
+		rows := make([][]string, 0, len(forks))
+		for _, fork := range forks {
+			rows = append(rows, []string{fork.GetFullName()})
+		}
+		if err := printTable(os.Stdout, []string{"FORK"}, rows); err != nil {
 			return err
 		}
+		expected := syncConfirmationText(forks)
+		ok, err := confirmTyped(fmt.Sprintf("Sync %d forks of %s", len(forks), expected), expected)
 		if err != nil {
-			log.Errorf("%s: syncing fork: %s", fork.GetFullName(), err)
-			retErr = errors.Join(retErr, fmt.Errorf("syncing fork %s: %w", fork.GetFullName(), err))
-			failed++
-			continue
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	pool := newWorkerPool(cmd.Context(), flags.parallel, syncer.sync)
+	for i, fork := range forks {
+		c, _, err := clients.forOwner(cmd.Context(), forkOwners[i])
+		if err != nil {
+			return err
+		}
+		if err := pool.Submit(cmd.Context(), forkJob{index: i, fork: fork, client: c}); err != nil {
+			break
+		}
+	}
+	pool.Wait()
+	if err := cmd.Context().Err(); err != nil {
+		return err
+	}
+
+	counts, retErr := tallyForkResults(forks, syncer.results)
+	if errors.Is(retErr, context.Canceled) {
+		return retErr
+	}
+
+	var planned, report [][]string
+	var plannedBranches, plannedTags int
+	for i, result := range syncer.results {
+		if flags.dryRun {
+			planned = append(planned, plannedSyncRows(forks[i].GetFullName(), result)...)
+			plannedTags += len(result.tags)
+		}
+		for _, b := range result.branches {
+			switch {
+			case b.err != nil:
+			case flags.report:
+				report = append(report, forkReportRow(forks[i].GetFullName(), b.branch.fork, b.status))
+			case flags.dryRun:
+				plannedBranches++
+			}
+		}
+	}
+
+	if flags.report {
+		if len(report) > 0 {
+			if err := printForkReport(os.Stdout, report); err != nil {
+				return err
+			}
 		}
+		return retErr
+	}
 
-		log.Infof("synced fork %s: %s", fork.GetFullName(), result.GetMessage())
+	if flags.dryRun {
+		fmt.Printf("Would sync %d branches and create %d tags, %d forks skipped:\n",
+			plannedBranches, plannedTags, counts.skippedForks)
+		if len(planned) == 0 {
+			return nil
+		}
+		return printTable(os.Stdout, []string{"FORK", "TARGET BRANCH", "UPSTREAM BRANCH", "TAGS"}, planned)
+	}
 
-		switch result.GetMergeType() {
-		case "fast-forward":
-			fastforwarded++
-		case "merge":
-			merged++
-		case "none":
-			notbehind++
+	now := time.Now()
+	for i, result := range syncer.results {
+		if forkSynced(result) {
+			state.record(forks[i], now)
 		}
 	}
+	if err := state.save(); err != nil {
+		log.Warnf("recording the sync times in %s: %s", statePath, err)
+	}
 
 	log.Infof(
-		"synced %d forks: %d up-to-date, %d fast-forwarded, %d merged, %d skipped, %d failed",
-		len(forks), notbehind, fastforwarded, merged, skipped, failed,
+		"synced %d of %d forks, %d skipped, %d failed; branches: %d up-to-date, %d fast-forwarded, %d merged, %d failed",
+		counts.syncedForks, len(forks), counts.skippedForks, counts.failedForks,
+		counts.upToDate, counts.fastForwarded, counts.merged, counts.failedBranches,
 	)
 	return retErr
 }
 
-func filterIgnoredRepos(repos []*github.Repository, ignoreRepos []string) []*github.Repository {
-	var filtered []*github.Repository
-outer:
-	for _, repo := range repos {
-		for _, ignore := range ignoreRepos {
-			if strings.EqualFold(repo.GetName(), ignore) {
-				continue outer
+// plannedSyncRows returns the rows of the dry-run table for the fork: one row per
+// branch, and the tags that would be created in the first row.
+func plannedSyncRows(fork string, result forkSyncResult) [][]string {
+	var tags string
+	if len(result.tags) > 0 {
+		tags = fmt.Sprintf("would create %d", len(result.tags))
+	}
+	var rows [][]string
+	for _, b := range result.branches {
+		if b.err != nil {
+			continue
+		}
+		rows = append(rows, []string{fork, b.branch.fork, b.branch.upstream, tags})
+		tags = ""
+	}
+	if tags != "" {
+		rows = append(rows, []string{fork, "", "", tags})
+	}
+	return rows
+}
+
+// forkSynced reports whether all branches of the fork were synced without error.
+func forkSynced(result forkSyncResult) bool {
+	if result.err != nil || len(result.branches) == 0 {
+		return false
+	}
+	for _, b := range result.branches {
+		if b.err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// forkSyncCounts counts the outcomes of a sync. Every fork is counted once as synced,
+// skipped or failed, and every branch of the synced and failed forks once by its outcome.
+type forkSyncCounts struct {
+	syncedForks  int
+	skippedForks int
+	failedForks  int
+
+	upToDate       int
+	fastForwarded  int
+	merged         int
+	failedBranches int
+}
+
+// tallyForkResults counts the results of the forks and joins their errors.
+func tallyForkResults(forks []*github.Repository, results []forkSyncResult) (forkSyncCounts, error) {
+	var counts forkSyncCounts
+	var retErr error
+	for i, result := range results {
+		failed := false
+		if errors.Is(result.err, context.Canceled) {
+			return counts, result.err
+		}
+		if result.err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("fork %s: %w", forks[i].GetFullName(), result.err))
+			failed = true
+		}
+		for _, b := range result.branches {
+			switch {
+			case errors.Is(b.err, context.Canceled):
+				return counts, b.err
+			case b.err != nil:
+				retErr = errors.Join(retErr, fmt.Errorf("syncing fork %s branch %s: %w",
+					forks[i].GetFullName(), b.branch, b.err))
+				counts.failedBranches++
+				failed = true
+			case b.mergeType == "fast-forward":
+				counts.fastForwarded++
+			case b.mergeType == "merge":
+				counts.merged++
+			case b.mergeType == "none":
+				counts.upToDate++
 			}
 		}
-		filtered = append(filtered, repo)
+		switch {
+		case failed:
+			counts.failedForks++
+		case len(result.branches) == 0:
+			counts.skippedForks++
+		default:
+			counts.syncedForks++
+		}
+	}
+	return counts, retErr
+}
+
+// syncConfirmationText returns the text the user must type to confirm the sync:
+// the fork, the common owner of the forks, or "sync".
+func syncConfirmationText(forks []*github.Repository) string {
+	if len(forks) == 1 {
+		return forks[0].GetFullName()
+	}
+	owner := forks[0].GetOwner().GetLogin()
+	for _, fork := range forks[1:] {
+		if !strings.EqualFold(fork.GetOwner().GetLogin(), owner) {
+			return "sync"
+		}
+	}
+	return owner
+}
+
+// filterConfiguredForks removes the forks that are ignored in the forks file or that
+// aren't due according to their schedule and the last sync recorded in the state.
+func filterConfiguredForks(forks []*github.Repository, config *forksConfig, state *syncState, ignoreSchedule bool,
+	now time.Time, log loggerI,
+) []*github.Repository {
+	var filtered []*github.Repository
+	for _, fork := range forks {
+		cfg := config.lookup(fork)
+		switch {
+		case cfg == nil:
+		case cfg.ignore:
+			log.Debugf("%s: ignored in forks file", fork.GetFullName())
+			continue
+		case !ignoreSchedule && !cfg.due(state.lastSync(fork), now):
+			log.Infof("%s: not due according to the %s schedule, skipping", fork.GetFullName(), cfg.schedule)
+			continue
+		}
+		filtered = append(filtered, fork)
 	}
 	return filtered
 }
 
+// listForks returns the forks of the owners selected with '--owner', listed with the
+// client of each owner, or the forks of the authenticated user with the affiliation.
+func listForks(ctx context.Context, clients *ownerClients, flags *syncForksFlags, log loggerI) ([]*github.Repository, error) {
+	if len(flags.selector.Owners) == 0 {
+		c, _, err := clients.forOwner(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("listing forks with affiliation %s", flags.affiliation)
+		forks, err := c.GetUserForks(ctx, flags.affiliation)
+		if err != nil {
+			return nil, fmt.Errorf("listing forks: %w", err)
+		}
+		return forks, nil
+	}
+
+	var forks []*github.Repository
+	listed := map[string]bool{}
+	for _, owner := range flags.selector.Owners {
+		if listed[strings.ToLower(owner)] {
+			continue
+		}
+		listed[strings.ToLower(owner)] = true
+		c, _, err := clients.forOwner(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("listing forks of %s", owner)
+		ownerForks, err := c.GetOwnerForks(ctx, owner)
+		if err != nil {
+			return nil, fmt.Errorf("listing forks of %s: %w", owner, err)
+		}
+		forks = append(forks, ownerForks...)
+	}
+	return forks, nil
+}
+
+type forkJob struct {
+	index  int
+	fork   *github.Repository
+	client *githubClient
+}
+
+// forkSyncResult is the outcome of syncing one fork. A fork without branches and
+// without error was skipped.
+type forkSyncResult struct {
+	branches []branchSyncResult
+	// tags are the tags created in the fork, or that would be created with '--dry-run'.
+	tags []string
+	// err is set if finding the target branches or syncing the tags configured with
+	// syncTags failed.
+	err error
+}
+
+// branchSyncResult is the outcome of syncing one branch of a fork.
+type branchSyncResult struct {
+	branch    branchMapping
+	mergeType string
+	// status is only set for '--report'.
+	status *forkStatus
+	err    error
+}
+
+// forkSyncer syncs forks concurrently. Every fork writes its result to its own
+// slot of results, so no locking is needed.
+type forkSyncer struct {
+	flags   *syncForksFlags
+	config  *forksConfig
+	logs    *logGroups
+	results []forkSyncResult
+}
+
+func (s *forkSyncer) sync(ctx context.Context, job forkJob) {
+	log := s.logs.group()
+	defer log.Flush()
+	s.results[job.index] = s.syncFork(ctx, job.client.withLogger(log), job.fork, log)
+}
+
+func (s *forkSyncer) syncFork(ctx context.Context, c *githubClient, fork *github.Repository, log loggerI) forkSyncResult {
+	cfg := s.config.lookup(fork)
+	var result forkSyncResult
+	branches, err := s.targetBranches(ctx, c, fork, cfg, log)
+	if err != nil {
+		log.Errorf("%s: finding target branches: %s", fork.GetFullName(), err)
+		return forkSyncResult{err: fmt.Errorf("finding target branches: %w", err)}
+	}
+	for _, branch := range branches {
+		b := s.syncBranch(ctx, c, fork, branch, log)
+		result.branches = append(result.branches, b)
+		if errors.Is(b.err, context.Canceled) {
+			return result
+		}
+	}
+
+	if cfg != nil && cfg.syncTags && !s.flags.report {
+		tags, err := syncTags(ctx, c, fork)
+		result.tags = tags
+		if err != nil {
+			log.Errorf("%s: syncing tags: %s", fork.GetFullName(), err)
+			result.err = fmt.Errorf("syncing tags: %w", err)
+		} else if len(tags) > 0 && !s.flags.dryRun {
+			log.Infof("%s: created %d tags of upstream", fork.GetFullName(), len(tags))
+		}
+	}
+	return result
+}
+
+// targetBranches returns the branches of the fork to sync. With '--branch-map', these are
+// all mapped branches that exist in the fork, otherwise the first existing target branch.
+// The configuration of the fork in the forks file takes precedence over the flags.
+// Only branches that don't exist are skipped, other errors are returned.
+func (s *forkSyncer) targetBranches(ctx context.Context, c *githubClient, fork *github.Repository, cfg *forkConfig, log loggerI) ([]branchMapping, error) {
+	if cfg == nil {
+		cfg = &forkConfig{}
+	}
+	if len(s.flags.branchMap) > 0 && len(cfg.targetBranches) == 0 && cfg.upstreamBranch == "" {
+		var branches []branchMapping
+		for _, mapping := range s.flags.branchMap {
+			log.Debugf("%s: checking if branch %q exists", fork.GetFullName(), mapping.fork)
+			_, err := c.GetBranch(ctx, fork, mapping.fork)
+			if errors.Is(err, ErrNotFound) {
+				log.Warnf("%s: branch %q not found, skipping", fork.GetFullName(), mapping.fork)
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			mapping.ffOnly = mapping.ffOnly || cfg.ffOnly
+			branches = append(branches, mapping)
+		}
+		return branches, nil
+	}
+
+	targetBranches, dontTargetDefault := s.flags.targetBranches, s.flags.dontTargetDefault
+	if len(cfg.targetBranches) > 0 {
+		targetBranches, dontTargetDefault = cfg.targetBranches, true
+	}
+
+	var branch string
+	if !dontTargetDefault {
+		branch = fork.GetDefaultBranch()
+		log.Debugf("%s: default branch is %s", fork.GetFullName(), branch)
+	}
+
+	for _, targetBranch := range targetBranches {
+		log.Debugf("%s: checking if branch %q exists", fork.GetFullName(), targetBranch)
+		_, err := c.GetBranch(ctx, fork, targetBranch)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		branch = targetBranch
+		log.Debugf("%s: using target branch %q", fork.GetFullName(), branch)
+		break
+	}
+
+	if branch == "" {
+		log.Warnf("%s: no target branch found, skipping", fork.GetFullName())
+		return nil, nil
+	}
+	upstream := branch
+	if cfg.upstreamBranch != "" {
+		upstream = cfg.upstreamBranch
+	}
+	return []branchMapping{{fork: branch, upstream: upstream, ffOnly: cfg.ffOnly}}, nil
+}
+
+func (s *forkSyncer) syncBranch(ctx context.Context, c *githubClient, fork *github.Repository, branch branchMapping, log loggerI) branchSyncResult {
+	if s.flags.report {
+		status, err := getForkStatus(ctx, c, fork, branch)
+		if err != nil {
+			log.Errorf("%s: comparing %s with upstream: %s", fork.GetFullName(), branch, err)
+			return branchSyncResult{branch: branch, err: err}
+		}
+		for _, file := range status.conflicts {
+			log.Debugf("%s: %s changed in fork and upstream", fork.GetFullName(), file)
+		}
+		return branchSyncResult{branch: branch, status: status}
+	}
+
+	if s.flags.dryRun {
+		return branchSyncResult{branch: branch}
+	}
+
+	log.Infof("%s: syncing fork branch %s with upstream", fork.GetFullName(), branch)
+	var mergeType, message string
+	if !branch.fastForwardOnly() {
+		result, err := c.SyncFork(ctx, fork, branch.fork)
+		if err != nil {
+			log.Errorf("%s: syncing fork: %s", fork.GetFullName(), err)
+			return branchSyncResult{branch: branch, err: err}
+		}
+		mergeType, message = result.GetMergeType(), result.GetMessage()
+	} else {
+		// Merging upstream only works between branches of the same name and may create a merge commit.
+		var err error
+		mergeType, err = fastForwardBranch(ctx, c, fork, branch)
+		if err != nil {
+			log.Errorf("%s: syncing fork: %s", fork.GetFullName(), err)
+			return branchSyncResult{branch: branch, err: err}
+		}
+		message = fmt.Sprintf("%s: %s", branch, mergeType)
+	}
+
+	log.Infof("synced fork %s: %s", fork.GetFullName(), message)
+	return branchSyncResult{branch: branch, mergeType: mergeType}
+}
+
+// fastForwardBranch updates the fork branch to the upstream branch if the fork branch
+// doesn't have commits missing upstream. It returns the merge type like SyncFork.
+func fastForwardBranch(ctx context.Context, c *githubClient, fork *github.Repository, branch branchMapping) (string, error) {
+	parent, err := c.GetParent(ctx, fork)
+	if err != nil {
+		return "", err
+	}
+	upstream, err := c.GetBranch(ctx, parent, branch.upstream)
+	if err != nil {
+		return "", fmt.Errorf("getting upstream branch %s: %w", branch.upstream, err)
+	}
+	sha := upstream.GetCommit().GetSHA()
+
+	comparison, err := c.CompareCommits(ctx, fork, branch.fork, sha)
+	if err != nil {
+		return "", err
+	}
+	switch comparison.GetStatus() {
+	case "identical", "behind":
+		return "none", nil
+	case "ahead":
+		if err := c.UpdateBranch(ctx, fork, branch.fork, sha); err != nil {
+			return "", err
+		}
+		return "fast-forward", nil
+	default:
+		return "", fmt.Errorf("branch %s has %d commits missing in upstream branch %s, can't fast-forward",
+			branch.fork, comparison.GetBehindBy(), branch.upstream)
+	}
+}
+
+// syncTags creates the tags of the parent that are missing in the fork, pointing to the
+// same commits. Tags existing in both aren't changed. It returns the created tags, or
+// the tags that would be created if the client is in dry-run mode.
+func syncTags(ctx context.Context, c *githubClient, fork *github.Repository) ([]string, error) {
+	parent, err := c.GetParent(ctx, fork)
+	if err != nil {
+		return nil, err
+	}
+	upstreamTags, err := c.GetTags(ctx, parent)
+	if err != nil {
+		return nil, fmt.Errorf("listing upstream tags: %w", err)
+	}
+	forkTags, err := c.GetTags(ctx, fork)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+
+	existing := map[string]bool{}
+	for _, tag := range forkTags {
+		existing[tag.GetName()] = true
+	}
+	var created []string
+	for _, tag := range upstreamTags {
+		if existing[tag.GetName()] {
+			continue
+		}
+		if err := c.CreateTag(ctx, fork, tag.GetName(), tag.GetCommit().GetSHA()); err != nil {
+			return created, err
+		}
+		created = append(created, tag.GetName())
+	}
+	return created, nil
+}
+
+// branchMapping maps a branch of the fork to the upstream branch it is synced with.
+type branchMapping struct {
+	fork     string
+	upstream string
+	// ffOnly forbids merging if the branch can't be fast-forwarded.
+	ffOnly bool
+}
+
+// fastForwardOnly reports whether the branch may only be fast-forwarded. Merging upstream
+// is only supported between branches of the same name.
+func (m branchMapping) fastForwardOnly() bool {
+	return m.ffOnly || m.fork != m.upstream
+}
+
+func (m branchMapping) String() string {
+	if m.fork == m.upstream {
+		return fmt.Sprintf("%q", m.fork)
+	}
+	return fmt.Sprintf("%q (upstream %q)", m.fork, m.upstream)
+}
+
+// parseBranchMapping parses a mapping like 'main=upstream:master'. The 'upstream:' prefix
+// is optional, and a single branch name is mapped to the upstream branch of the same name.
+func parseBranchMapping(s string) (branchMapping, error) {
+	forkBranch, upstreamBranch, ok := strings.Cut(s, "=")
+	if !ok {
+		upstreamBranch = forkBranch
+	}
+	upstreamBranch = strings.TrimPrefix(upstreamBranch, "upstream:")
+	if forkBranch == "" || upstreamBranch == "" {
+		return branchMapping{}, fmt.Errorf("invalid branch mapping %q, expected FORK-BRANCH=upstream:UPSTREAM-BRANCH", s)
+	}
+	return branchMapping{fork: forkBranch, upstream: upstreamBranch}, nil
+}
+
+func filterIgnoredRepos(repos []*github.Repository, ignoreRepos []string) []*github.Repository {
+	s := &selector.Selector{}
+	for _, ignore := range ignoreRepos {
+		s.Exclude = append(s.Exclude, selector.Literal(ignore))
+	}
+	return s.Filter(repos)
+}
+
 type syncForksFlags struct {
 	verbose           bool
+	dryRun            bool
 	ignoreRepos       []string
+	selector          *selector.Selector
+	parallel          int
+	yes               bool
+	report            bool
+	affiliation       string
+	branchMap         []branchMapping
+	forksFile         string
+	ignoreSchedule    bool
 	targetBranches    []string
 	dontTargetDefault bool
 }
@@ -165,10 +742,18 @@ func parseSyncForksFlags(cmd *cobra.Command) (*syncForksFlags, error) {
 	if err != nil {
 		return nil, err
 	}
+	flags.dryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
 	flags.ignoreRepos, err = cmd.Flags().GetStringSlice("ignore-repos")
 	if err != nil {
 		return nil, err
 	}
+	flags.selector, err = selector.FromFlags(cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
 	flags.targetBranches, err = cmd.Flags().GetStringSlice("target-branches")
 	if err != nil {
 		return nil, err
@@ -177,6 +762,55 @@ func parseSyncForksFlags(cmd *cobra.Command) (*syncForksFlags, error) {
 	if err != nil {
 		return nil, err
 	}
+	affiliations, err := cmd.Flags().GetStringSlice("affiliation")
+	if err != nil {
+		return nil, err
+	}
+	for _, affiliation := range affiliations {
+		switch affiliation {
+		case "owner", "collaborator", "organization_member":
+		default:
+			return nil, fmt.Errorf("invalid affiliation %q, expected owner, collaborator or organization_member", affiliation)
+		}
+	}
+	if cmd.Flags().Changed("affiliation") && len(flags.selector.Owners) > 0 {
+		return nil, errors.New("'--affiliation' can't be used with '--owner'")
+	}
+	flags.affiliation = strings.Join(affiliations, ",")
+	branchMap, err := cmd.Flags().GetStringSlice("branch-map")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range branchMap {
+		mapping, err := parseBranchMapping(entry)
+		if err != nil {
+			return nil, err
+		}
+		flags.branchMap = append(flags.branchMap, mapping)
+	}
+	flags.forksFile, err = cmd.Flags().GetString("forks-file")
+	if err != nil {
+		return nil, err
+	}
+	flags.ignoreSchedule, err = cmd.Flags().GetBool("ignore-schedule")
+	if err != nil {
+		return nil, err
+	}
+	flags.report, err = cmd.Flags().GetBool("report")
+	if err != nil {
+		return nil, err
+	}
+	flags.yes, err = cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
+	}
+	flags.parallel, err = cmd.Flags().GetInt("parallel")
+	if err != nil {
+		return nil, err
+	}
+	if flags.parallel < 1 {
+		return nil, errors.New("'--parallel' must be at least 1")
+	}
 
 	if flags.dontTargetDefault && len(flags.targetBranches) == 0 {
 		return nil, errors.New("'--target-branches' must be set when using '--dont-target-default'")
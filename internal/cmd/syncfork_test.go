@@ -1,11 +1,23 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v61/github"
+	"github.com/katexochen/ghh/internal/selector"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 )
 
 func TestFilterIgnoredRepos(t *testing.T) {
@@ -37,3 +49,429 @@ func TestFilterIgnoredRepos(t *testing.T) {
 		})
 	}
 }
+
+func TestForkSyncer(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	mergeTypes := map[string]string{"ff": "fast-forward", "merge": "merge", "uptodate": "none"}
+	var inFlight, maxInFlight atomic.Int32
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		// /api/v3/repos/owner/<name>/<endpoint>...
+		parts := strings.Split(r.URL.Path, "/")
+		name := parts[5]
+		switch {
+		case name == "denied":
+			w.WriteHeader(http.StatusUnauthorized)
+		case parts[6] == "branches":
+			if parts[7] == "sync" && name != "nodefault" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"name": %q}`, parts[7])
+		case parts[6] == "merge-upstream" && name == "conflict":
+			w.WriteHeader(http.StatusConflict)
+		case parts[6] == "merge-upstream":
+			fmt.Fprintf(w, `{"merge_type": %q}`, mergeTypes[strings.TrimRight(name, "0123456789")])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	var forks []*github.Repository
+	for _, name := range []string{"ff1", "ff2", "merge1", "uptodate1", "uptodate2", "uptodate3", "conflict", "denied", "nodefault"} {
+		forks = append(forks, &github.Repository{
+			Name:          toPtr(name),
+			FullName:      toPtr("owner/" + name),
+			Owner:         &github.User{Login: toPtr("owner")},
+			Fork:          toPtr(true),
+			DefaultBranch: toPtr("main"),
+		})
+	}
+	forks[len(forks)-1].DefaultBranch = nil
+
+	syncer := &forkSyncer{
+		flags:   &syncForksFlags{targetBranches: []string{"sync"}, dontTargetDefault: true, parallel: 4},
+		logs:    newLogGroups(newLogger(false)),
+		results: make([]forkSyncResult, len(forks)),
+	}
+	// Only nodefault has the target branch, all others are skipped without a default branch.
+	// Checking the branches of denied fails, so it isn't skipped but failed.
+	pool := newWorkerPool(context.Background(), syncer.flags.parallel, syncer.sync)
+	for i, fork := range forks {
+		require.NoError(pool.Submit(context.Background(), forkJob{index: i, fork: fork, client: c}))
+	}
+	pool.Wait()
+
+	for i, result := range syncer.results[:len(forks)-1] {
+		assert.Empty(result.branches, forks[i].GetName())
+		if forks[i].GetName() == "denied" {
+			assert.Error(result.err)
+		} else {
+			assert.NoError(result.err, forks[i].GetName())
+		}
+	}
+	require.Len(syncer.results[len(forks)-1].branches, 1)
+	assert.Equal(branchMapping{fork: "sync", upstream: "sync"}, syncer.results[len(forks)-1].branches[0].branch)
+
+	syncer.flags = &syncForksFlags{parallel: 4}
+	syncer.results = make([]forkSyncResult, len(forks))
+	pool = newWorkerPool(context.Background(), syncer.flags.parallel, syncer.sync)
+	for i, fork := range forks {
+		require.NoError(pool.Submit(context.Background(), forkJob{index: i, fork: fork, client: c}))
+	}
+	pool.Wait()
+
+	counts, err := tallyForkResults(forks, syncer.results)
+	assert.ErrorContains(err, "owner/conflict")
+	assert.ErrorContains(err, "owner/denied")
+	assert.Equal(forkSyncCounts{
+		syncedForks: 6, skippedForks: 1, failedForks: 2,
+		upToDate: 3, fastForwarded: 2, merged: 1, failedBranches: 2,
+	}, counts)
+	assert.LessOrEqual(maxInFlight.Load(), int32(4))
+	assert.Greater(maxInFlight.Load(), int32(1))
+}
+
+func TestListForks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch r.URL.Path {
+		case "/api/v3/user":
+			// Only the token of the user has a user.
+			if token != "token-me" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"login": "me", "type": "User"}`)
+		case "/api/v3/users/me":
+			fmt.Fprint(w, `{"login": "me", "type": "User"}`)
+		case "/api/v3/users/other":
+			fmt.Fprint(w, `{"login": "other", "type": "User"}`)
+		case "/api/v3/users/myorg":
+			fmt.Fprint(w, `{"login": "myorg", "type": "Organization"}`)
+		case "/api/v3/user/repos":
+			assert.Equal(t, "token-me", token)
+			if r.URL.Query().Get("affiliation") == "owner" {
+				fmt.Fprint(w, `[{"full_name": "me/fork", "fork": true}, {"full_name": "me/private-fork", "fork": true},
+					{"full_name": "me/own", "fork": false}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"full_name": "me/fork", "fork": true}, {"full_name": "team/shared-fork", "fork": true}]`)
+		case "/api/v3/orgs/myorg/repos":
+			assert.Equal(t, "token-myorg", token)
+			assert.Equal(t, "forks", r.URL.Query().Get("type"))
+			fmt.Fprint(w, `[{"full_name": "myorg/vendored", "fork": true}]`)
+		case "/api/v3/users/other/repos":
+			assert.Equal(t, "token-other", token)
+			fmt.Fprint(w, `[{"full_name": "other/fork", "fork": true}, {"full_name": "other/own", "fork": false}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	// Every owner has its own token, the forks of the user are listed with the token of the user.
+	p := &profile{Host: "ghe.example.com", APIURL: server.URL + "/api/v3/"}
+	clients := newOwnerClients(&cobra.Command{}, p, newLogger(false), false)
+	for owner, token := range map[string]string{"": "token-me", "me": "token-me", "myorg": "token-myorg", "other": "token-other"} {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		c, err := newGithubClient(owner, "", p, ts, newLogger(false), false)
+		require.NoError(t, err)
+		clients.clients[owner] = c
+		clients.tokenSources[owner] = ts
+	}
+
+	testCases := []struct {
+		owners      []string
+		affiliation string
+		want        []string
+	}{
+		{ // authenticated user
+			affiliation: "owner",
+			want:        []string{"me/fork", "me/private-fork"},
+		},
+		{ // collaborator
+			affiliation: "owner,collaborator",
+			want:        []string{"me/fork", "team/shared-fork"},
+		},
+		{ // owners
+			owners: []string{"myorg", "other", "me", "MyOrg"},
+			want:   []string{"myorg/vendored", "other/fork", "me/fork", "me/private-fork"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			flags := &syncForksFlags{
+				selector:    &selector.Selector{Owners: tc.owners},
+				affiliation: tc.affiliation,
+			}
+			forks, err := listForks(context.Background(), clients, flags, newLogger(false))
+			assert.NoError(err)
+			var names []string
+			for _, fork := range forks {
+				names = append(names, fork.GetFullName())
+			}
+			assert.Equal(tc.want, names)
+		})
+	}
+}
+
+func TestParseBranchMapping(t *testing.T) {
+	testCases := []struct {
+		mapping string
+		want    branchMapping
+		wantErr bool
+	}{
+		{mapping: "main=upstream:master", want: branchMapping{fork: "main", upstream: "master"}},         // upstream prefix
+		{mapping: "main=master", want: branchMapping{fork: "main", upstream: "master"}},                  // without prefix
+		{mapping: "release-1.2", want: branchMapping{fork: "release-1.2", upstream: "release-1.2"}},      // same name
+		{mapping: "ci/main=upstream:feat/ci", want: branchMapping{fork: "ci/main", upstream: "feat/ci"}}, // slashes
+		{mapping: "=upstream:master", wantErr: true},                                                     // empty fork branch
+		{mapping: "main=upstream:", wantErr: true},                                                       // empty upstream
+		{mapping: "", wantErr: true},                                                                     // empty mapping
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			mapping, err := parseBranchMapping(tc.mapping)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, mapping)
+		})
+	}
+}
+
+func TestFastForwardBranch(t *testing.T) {
+	testCases := []struct {
+		status        string
+		wantMergeType string
+		wantUpdate    bool
+		wantErr       bool
+	}{
+		{status: "ahead", wantMergeType: "fast-forward", wantUpdate: true}, // behind upstream
+		{status: "identical", wantMergeType: "none"},                       // identical
+		{status: "behind", wantMergeType: "none"},                          // ahead upstream
+		{status: "diverged", wantErr: true},                                // diverged
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			var updated string
+			c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/api/v3/repos/me/repo":
+					fmt.Fprint(w, `{"name": "repo", "parent": {"name": "repo", "full_name": "upstream/repo", "owner": {"login": "upstream"}}}`)
+				case r.URL.Path == "/api/v3/repos/upstream/repo/branches/master":
+					fmt.Fprint(w, `{"name": "master", "commit": {"sha": "abc123"}}`)
+				case r.URL.Path == "/api/v3/repos/me/repo/compare/main...abc123":
+					fmt.Fprintf(w, `{"status": %q, "behind_by": 2}`, tc.status)
+				case r.URL.Path == "/api/v3/repos/me/repo/git/refs/heads/main" && r.Method == http.MethodPatch:
+					body, _ := io.ReadAll(r.Body)
+					updated = string(body)
+					fmt.Fprint(w, `{"ref": "refs/heads/main"}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			fork := &github.Repository{Name: toPtr("repo"), Owner: &github.User{Login: toPtr("me")}, Fork: toPtr(true)}
+			mergeType, err := fastForwardBranch(context.Background(), c, fork, branchMapping{fork: "main", upstream: "master"})
+			if tc.wantErr {
+				assert.Error(err)
+				assert.Empty(updated)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantMergeType, mergeType)
+			if tc.wantUpdate {
+				assert.JSONEq(`{"sha": "abc123", "force": false}`, updated)
+			} else {
+				assert.Empty(updated)
+			}
+		})
+	}
+}
+
+func TestSyncTags(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	var created []string
+	c := newTestGithubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/repos/me/repo":
+			fmt.Fprint(w, `{"name": "repo", "parent": {"name": "repo", "full_name": "upstream/repo", "owner": {"login": "upstream"}}}`)
+		case r.URL.Path == "/api/v3/repos/upstream/repo/tags":
+			fmt.Fprint(w, `[{"name": "v1.0.0", "commit": {"sha": "aaa"}}, {"name": "v1.1.0", "commit": {"sha": "bbb"}},
+				{"name": "v2.0.0", "commit": {"sha": "ccc"}}]`)
+		case r.URL.Path == "/api/v3/repos/me/repo/tags":
+			fmt.Fprint(w, `[{"name": "v1.0.0", "commit": {"sha": "aaa"}}, {"name": "v1.1.0", "commit": {"sha": "patched"}}]`)
+		case r.URL.Path == "/api/v3/repos/me/repo/git/refs" && r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			created = append(created, string(body))
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	fork := &github.Repository{Name: toPtr("repo"), Owner: &github.User{Login: toPtr("me")}, Fork: toPtr(true)}
+	c.dryRun = true
+	tags, err := syncTags(context.Background(), c, fork)
+	assert.NoError(err)
+	assert.Equal([]string{"v2.0.0"}, tags)
+	assert.Empty(created)
+
+	c.dryRun = false
+	tags, err = syncTags(context.Background(), c, fork)
+	assert.NoError(err)
+	assert.Equal([]string{"v2.0.0"}, tags)
+	require.Len(created, 1)
+	assert.JSONEq(`{"ref": "refs/tags/v2.0.0", "sha": "ccc"}`, created[0])
+}
+
+func TestSyncConfirmationText(t *testing.T) {
+	fork := func(owner, name string) *github.Repository {
+		return &github.Repository{Name: toPtr(name), FullName: toPtr(owner + "/" + name), Owner: &github.User{Login: toPtr(owner)}}
+	}
+
+	testCases := []struct {
+		forks []*github.Repository
+		want  string
+	}{
+		{ // single fork
+			forks: []*github.Repository{fork("me", "a")},
+			want:  "me/a",
+		},
+		{ // common owner
+			forks: []*github.Repository{fork("me", "a"), fork("Me", "b")},
+			want:  "me",
+		},
+		{ // different owners
+			forks: []*github.Repository{fork("me", "a"), fork("myorg", "b")},
+			want:  "sync",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.want, syncConfirmationText(tc.forks))
+		})
+	}
+}
+
+func TestTallyForkResults(t *testing.T) {
+	forks := []*github.Repository{{FullName: toPtr("me/a")}, {FullName: toPtr("me/b")}}
+	main := branchMapping{fork: "main", upstream: "main"}
+
+	testCases := []struct {
+		results    []forkSyncResult
+		wantCounts forkSyncCounts
+		wantErr    string
+	}{
+		{ // merge types
+			results: []forkSyncResult{
+				{branches: []branchSyncResult{{branch: main, mergeType: "fast-forward"}, {branch: main, mergeType: "none"}}},
+				{branches: []branchSyncResult{{branch: main, mergeType: "merge"}}},
+			},
+			wantCounts: forkSyncCounts{syncedForks: 2, upToDate: 1, fastForwarded: 1, merged: 1},
+		},
+		{ // skipped and failed
+			results: []forkSyncResult{
+				{},
+				{branches: []branchSyncResult{{branch: main, err: assert.AnError}}},
+			},
+			wantCounts: forkSyncCounts{skippedForks: 1, failedForks: 1, failedBranches: 1},
+			wantErr:    "syncing fork me/b branch \"main\"",
+		},
+		{ // fork failed
+			results: []forkSyncResult{
+				{branches: []branchSyncResult{{branch: main, mergeType: "none"}}, err: assert.AnError},
+				{err: assert.AnError},
+			},
+			wantCounts: forkSyncCounts{failedForks: 2, upToDate: 1},
+			wantErr:    "fork me/b",
+		},
+		{ // fork with failed branches counted once
+			results: []forkSyncResult{
+				{branches: []branchSyncResult{{branch: main, err: assert.AnError}, {branch: main, err: assert.AnError}}, err: assert.AnError},
+				{branches: []branchSyncResult{{branch: main, mergeType: "merge"}}},
+			},
+			wantCounts: forkSyncCounts{syncedForks: 1, failedForks: 1, merged: 1, failedBranches: 2},
+			wantErr:    "fork me/a",
+		},
+		{ // canceled
+			results: []forkSyncResult{
+				{branches: []branchSyncResult{{branch: main, err: context.Canceled}}},
+				{branches: []branchSyncResult{{branch: main, mergeType: "none"}}},
+			},
+			wantErr: context.Canceled.Error(),
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			counts, err := tallyForkResults(forks, tc.results)
+			if tc.wantErr != "" {
+				assert.ErrorContains(err, tc.wantErr)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantCounts, counts)
+		})
+	}
+}
+
+func TestPlannedSyncRows(t *testing.T) {
+	main := branchMapping{fork: "main", upstream: "main"}
+	release := branchMapping{fork: "release", upstream: "stable"}
+
+	testCases := []struct {
+		result forkSyncResult
+		want   [][]string
+	}{
+		{ // branches
+			result: forkSyncResult{branches: []branchSyncResult{{branch: main}, {branch: release}}},
+			want:   [][]string{{"me/a", "main", "main", ""}, {"me/a", "release", "stable", ""}},
+		},
+		{ // tags in first row
+			result: forkSyncResult{branches: []branchSyncResult{{branch: main}, {branch: release}}, tags: []string{"v1", "v2"}},
+			want:   [][]string{{"me/a", "main", "main", "would create 2"}, {"me/a", "release", "stable", ""}},
+		},
+		{ // only tags
+			result: forkSyncResult{tags: []string{"v1"}},
+			want:   [][]string{{"me/a", "", "", "would create 1"}},
+		},
+		{}, // skipped
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.want, plannedSyncRows("me/a", tc.result))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+)
+
+// syncState records when sync-forks last synced each fork, so the schedule of the forks
+// file doesn't have to rely on the last push to the fork.
+type syncState struct {
+	path string
+	// synced maps the lowercase full name of a fork to the time of its last sync.
+	synced map[string]time.Time
+}
+
+// loadSyncState reads the sync times recorded at path. A missing file results in an empty state.
+func loadSyncState(path string) (*syncState, error) {
+	s := &syncState{path: path, synced: map[string]time.Time{}}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, synced, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, synced)
+		if err != nil {
+			continue
+		}
+		s.synced[name] = t
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading sync state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// lastSync returns the time the fork was last synced. Without a recorded sync, the last
+// push to the fork approximates it.
+func (s *syncState) lastSync(fork *github.Repository) time.Time {
+	if s != nil {
+		if t, ok := s.synced[strings.ToLower(fork.GetFullName())]; ok {
+			return t
+		}
+	}
+	return fork.GetPushedAt().Time
+}
+
+// record sets the time the fork was last synced.
+func (s *syncState) record(fork *github.Repository, t time.Time) {
+	s.synced[strings.ToLower(fork.GetFullName())] = t
+}
+
+// save writes the sync times to the file, replacing it atomically.
+func (s *syncState) save() error {
+	names := make([]string, 0, len(s.synced))
+	for name := range s.synced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s %s\n", name, s.synced[name].UTC().Format(time.RFC3339))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func defaultSyncStatePath(host string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "ghh", "sync-forks", host), nil
+}
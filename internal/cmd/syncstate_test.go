@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncState(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "sync-forks", "github.com")
+
+	pushed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	synced := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	fork := &github.Repository{FullName: toPtr("Me/Fork"), PushedAt: &github.Timestamp{Time: pushed}}
+	other := &github.Repository{FullName: toPtr("me/other"), PushedAt: &github.Timestamp{Time: pushed}}
+
+	state, err := loadSyncState(path)
+	require.NoError(err)
+	assert.Equal(pushed, state.lastSync(fork))
+
+	state.record(fork, synced)
+	require.NoError(state.save())
+
+	state, err = loadSyncState(path)
+	require.NoError(err)
+	assert.Equal(synced, state.lastSync(&github.Repository{FullName: toPtr("me/fork")}))
+	assert.Equal(pushed, state.lastSync(other))
+
+	var nilState *syncState
+	assert.Equal(pushed, nilState.lastSync(fork))
+}
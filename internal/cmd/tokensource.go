@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tokenSourceGHHEnv = "ghh-env"
+	tokenSourceConfig = "config"
+	tokenSourceEnv    = "env"
+	tokenSourceGH     = "gh"
+	tokenSourceGit    = "git-credential"
+)
+
+// resolvedToken is a token together with a description of where it was found.
+type resolvedToken struct {
+	value  string
+	source string
+	// resolver is the name of the token source, see tokenResolvers.
+	resolver string
+}
+
+// tokenResolver looks up the token of a profile in one source.
+// It returns ErrCredentialNotFound if the source holds no token for the profile.
+type tokenResolver struct {
+	name    string
+	resolve func(p *profile) (*resolvedToken, error)
+}
+
+// tokenResolvers are consulted in order until one of them returns a token.
+var tokenResolvers = []tokenResolver{
+	{name: tokenSourceGHHEnv, resolve: resolveGHHEnvToken},
+	{name: tokenSourceConfig, resolve: resolveConfigToken},
+	{name: tokenSourceEnv, resolve: resolveEnvToken},
+	{name: tokenSourceGH, resolve: resolveGHToken},
+	{name: tokenSourceGit, resolve: resolveGitCredentialToken},
+}
+
+func tokenSourceNames() []string {
+	names := make([]string, 0, len(tokenResolvers))
+	for _, r := range tokenResolvers {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// resolveToken returns the token for the given profile. If the profile forces a token
+// source, only that source is consulted and its errors are returned. Otherwise, the first
+// source holding a token wins, and sources that fail are logged and skipped.
+func resolveToken(p *profile, log loggerI) (*resolvedToken, error) {
+	if p.tokenSource != "" {
+		for _, r := range tokenResolvers {
+			if r.name != p.tokenSource {
+				continue
+			}
+			token, err := r.resolve(p)
+			if errors.Is(err, ErrCredentialNotFound) {
+				return nil, fmt.Errorf("no token for %s found in token source %q", p.Host, r.name)
+			} else if err != nil {
+				return nil, fmt.Errorf("token source %s: %w", r.name, err)
+			}
+			token.resolver = r.name
+			return token, nil
+		}
+		return nil, fmt.Errorf("unknown token source %q, must be one of %s", p.tokenSource, strings.Join(tokenSourceNames(), ", "))
+	}
+
+	var failed []string
+	for _, r := range tokenResolvers {
+		token, err := r.resolve(p)
+		if errors.Is(err, ErrCredentialNotFound) {
+			continue
+		} else if err != nil {
+			log.Debugf("token source %s: %s", r.name, err)
+			failed = append(failed, r.name)
+			continue
+		}
+		token.resolver = r.name
+		return token, nil
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("no token found for %s, reading token sources %s failed, "+
+			"select one with '--token-source' to see the error", p.Host, strings.Join(failed, ", "))
+	}
+	return nil, fmt.Errorf("no token found for %s. Please set the GHH_TOKEN environment variable, "+
+		"run `ghh set-auth` or `ghh login`, or log in with the gh CLI", p.Host)
+}
+
+// getToken returns the token for the given profile.
+func getToken(p *profile, log loggerI) (string, error) {
+	token, err := resolveToken(p, log)
+	if err != nil {
+		return "", err
+	}
+	return token.value, nil
+}
+
+func resolveGHHEnvToken(_ *profile) (*resolvedToken, error) {
+	if token := os.Getenv(tokenEnvVar); token != "" {
+		return &resolvedToken{value: token, source: "environment variable " + tokenEnvVar}, nil
+	}
+	return nil, ErrCredentialNotFound
+}
+
+// resolveConfigToken reads the token from the credential store of the profile.
+func resolveConfigToken(p *profile) (*resolvedToken, error) {
+	if p.CredentialStore == "" {
+		return nil, ErrCredentialNotFound
+	}
+
+	store, err := newCredentialStore(p.CredentialStore)
+	if err != nil {
+		return nil, err
+	}
+	token, err := store.Get(p.Name())
+	if errors.Is(err, ErrCredentialNotFound) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("reading token from %s store: %w", p.CredentialStore, err)
+	}
+
+	source := fmt.Sprintf("%s store (config file %s)", store.Name(), configFilePath)
+	return &resolvedToken{value: token, source: source}, nil
+}
+
+// resolveEnvToken reads the token from the environment variables used by the gh CLI
+// and GitHub Actions. The enterprise variants are used for hosts other than github.com.
+func resolveEnvToken(p *profile) (*resolvedToken, error) {
+	vars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if p.IsEnterprise() {
+		vars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, v := range vars {
+		if token := os.Getenv(v); token != "" {
+			return &resolvedToken{value: token, source: "environment variable " + v}, nil
+		}
+	}
+	return nil, ErrCredentialNotFound
+}
+
+// resolveGHToken reads the token of the gh CLI from its hosts.yml. Newer versions of gh
+// keep the token in the system keyring, in that case it is requested with 'gh auth token'.
+func resolveGHToken(p *profile) (*resolvedToken, error) {
+	path, err := ghHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCredentialNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(raw, &hosts); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	host, ok := hosts[p.Host]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	if host.OAuthToken != "" {
+		return &resolvedToken{value: host.OAuthToken, source: "gh CLI config " + path}, nil
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	out, err := exec.Command("gh", "auth", "token", "--hostname", p.Host).Output()
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return nil, ErrCredentialNotFound
+	}
+	return &resolvedToken{value: token, source: "gh CLI keyring"}, nil
+}
+
+func ghHostsPath() (string, error) {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml"), nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml"), nil
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI", "hosts.yml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml"), nil
+}
+
+// resolveGitCredentialToken asks the configured git credential helpers for the
+// password of the host, without prompting the user.
+func resolveGitCredentialToken(p *profile) (*resolvedToken, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, ErrCredentialNotFound
+	}
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\n\n", p.Host))
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if password, ok := strings.CutPrefix(scanner.Text(), "password="); ok && password != "" {
+			return &resolvedToken{value: password, source: "git credential helper"}, nil
+		}
+	}
+	return nil, ErrCredentialNotFound
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveToken(t *testing.T) {
+	testCases := []struct {
+		env             map[string]string
+		host            string
+		tokenSource     string
+		expectedToken   string
+		expectedSource  string
+		ghHosts         string
+		credentialStore string
+		wantErr         string
+	}{
+		{
+			host:           "github.com",
+			expectedToken:  "gho_gh",
+			expectedSource: tokenSourceGH,
+		},
+		{
+			env:            map[string]string{"GITHUB_TOKEN": "ghp_env"},
+			host:           "github.com",
+			expectedToken:  "ghp_env",
+			expectedSource: tokenSourceEnv,
+		},
+		{
+			env:            map[string]string{"GITHUB_TOKEN": "ghp_env", tokenEnvVar: "ghp_ghh"},
+			host:           "github.com",
+			expectedToken:  "ghp_ghh",
+			expectedSource: tokenSourceGHHEnv,
+		},
+		{
+			env:            map[string]string{"GITHUB_TOKEN": "ghp_env"},
+			host:           "github.com",
+			tokenSource:    tokenSourceGH,
+			expectedToken:  "gho_gh",
+			expectedSource: tokenSourceGH,
+		},
+		{
+			env:            map[string]string{"GITHUB_TOKEN": "ghp_env", "GH_ENTERPRISE_TOKEN": "ghp_enterprise"},
+			host:           "ghe.example.com",
+			expectedToken:  "ghp_enterprise",
+			expectedSource: tokenSourceEnv,
+		},
+		{
+			host:        "github.com",
+			tokenSource: tokenSourceEnv,
+			wantErr:     `no token for github.com found in token source "env"`,
+		},
+		{
+			host:    "other.example.com",
+			wantErr: "no token found for other.example.com",
+		},
+		{
+			// A broken source doesn't stop the chain.
+			host:            "github.com",
+			credentialStore: "unknown",
+			expectedToken:   "gho_gh",
+			expectedSource:  tokenSourceGH,
+		},
+		{
+			host:    "github.com",
+			ghHosts: "github.com: [",
+			wantErr: "reading token sources gh failed",
+		},
+		{
+			host:        "github.com",
+			tokenSource: tokenSourceGH,
+			ghHosts:     "github.com: [",
+			wantErr:     "token source gh: parsing",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			for _, v := range []string{tokenEnvVar, "GITHUB_TOKEN", "GH_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+				t.Setenv(v, "")
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			// Keep git and gh of the host out of the test.
+			t.Setenv("PATH", "")
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			ghDir := t.TempDir()
+			t.Setenv("GH_CONFIG_DIR", ghDir)
+			hosts := "github.com:\n    user: octocat\n    oauth_token: gho_gh\n"
+			if tc.ghHosts != "" {
+				hosts = tc.ghHosts
+			}
+			require.NoError(os.WriteFile(filepath.Join(ghDir, "hosts.yml"), []byte(hosts), 0o600))
+
+			p := newProfile(tc.host, tc.host)
+			p.tokenSource = tc.tokenSource
+			p.CredentialStore = tc.credentialStore
+			token, err := resolveToken(p, newLogger(false))
+			if tc.wantErr != "" {
+				assert.ErrorContains(err, tc.wantErr)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.expectedToken, token.value)
+			assert.Equal(tc.expectedSource, token.resolver)
+		})
+	}
+}
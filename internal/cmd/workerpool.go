@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool runs work on a fixed number of goroutines for every submitted item.
+type workerPool[T any] struct {
+	jobs chan T
+	wg   sync.WaitGroup
+}
+
+// newWorkerPool starts n workers calling work for the submitted items.
+// Reporting results is up to work.
+func newWorkerPool[T any](ctx context.Context, n int, work func(context.Context, T)) *workerPool[T] {
+	if n < 1 {
+		n = 1
+	}
+	p := &workerPool[T]{jobs: make(chan T)}
+	for i := 0; i < n; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for item := range p.jobs {
+				work(ctx, item)
+			}
+		}()
+	}
+	return p
+}
+
+// Submit blocks until a worker picks up the item or the context is done.
+func (p *workerPool[T]) Submit(ctx context.Context, item T) error {
+	select {
+	case p.jobs <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait stops accepting items and waits until all submitted items are processed.
+func (p *workerPool[T]) Wait() {
+	close(p.jobs)
+	p.wg.Wait()
+}
@@ -0,0 +1,142 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// AddFlags adds the flags selecting repositories to the flag set.
+// Archived repositories are excluded by default.
+func AddFlags(fs *pflag.FlagSet) {
+	fs.StringArray(
+		"include",
+		nil,
+		"Only select repositories matching the glob or /regex/ pattern. Patterns with a slash "+
+			"match owner/name. Can be repeated.",
+	)
+	fs.StringArray(
+		"exclude",
+		nil,
+		"Skip repositories matching the glob or /regex/ pattern. Can be repeated.",
+	)
+	fs.StringSlice(
+		"owner",
+		nil,
+		"Only select repositories of the user or organization. Can be repeated.",
+	)
+	fs.StringSlice(
+		"topic",
+		nil,
+		"Only select repositories with one of the topics. Can be repeated.",
+	)
+	fs.StringSlice(
+		"language",
+		nil,
+		"Only select repositories with one of the primary languages. Can be repeated.",
+	)
+	fs.String(
+		"visibility",
+		"",
+		"Only select repositories with the visibility, one of public, private, internal",
+	)
+	fs.String(
+		"archived",
+		string(Exclude),
+		"Whether to select archived repositories, one of include, exclude, only",
+	)
+	fs.String(
+		"template",
+		"include",
+		"Whether to select template repositories, one of include, exclude, only",
+	)
+	fs.String(
+		"pushed-before",
+		"",
+		"Only select repositories last pushed before the date, e.g. '2024-01-31'",
+	)
+	fs.String(
+		"pushed-after",
+		"",
+		"Only select repositories last pushed after the date, e.g. '2024-01-31'",
+	)
+}
+
+// FromFlags creates a selector from the flags added by AddFlags.
+func FromFlags(fs *pflag.FlagSet) (*Selector, error) {
+	s := &Selector{}
+
+	include, err := fs.GetStringArray("include")
+	if err != nil {
+		return nil, err
+	}
+	if s.Include, err = ParsePatterns(include); err != nil {
+		return nil, fmt.Errorf("parsing '--include': %w", err)
+	}
+	exclude, err := fs.GetStringArray("exclude")
+	if err != nil {
+		return nil, err
+	}
+	if s.Exclude, err = ParsePatterns(exclude); err != nil {
+		return nil, fmt.Errorf("parsing '--exclude': %w", err)
+	}
+	if s.Owners, err = fs.GetStringSlice("owner"); err != nil {
+		return nil, err
+	}
+	if s.Topics, err = fs.GetStringSlice("topic"); err != nil {
+		return nil, err
+	}
+	if s.Languages, err = fs.GetStringSlice("language"); err != nil {
+		return nil, err
+	}
+
+	if s.Visibility, err = fs.GetString("visibility"); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(s.Visibility) {
+	case "", "public", "private", "internal":
+	default:
+		return nil, fmt.Errorf("invalid visibility %q, expected public, private or internal", s.Visibility)
+	}
+
+	archived, err := fs.GetString("archived")
+	if err != nil {
+		return nil, err
+	}
+	if s.Archived, err = ParseFilter(archived); err != nil {
+		return nil, fmt.Errorf("parsing '--archived': %w", err)
+	}
+	template, err := fs.GetString("template")
+	if err != nil {
+		return nil, err
+	}
+	if s.Template, err = ParseFilter(template); err != nil {
+		return nil, fmt.Errorf("parsing '--template': %w", err)
+	}
+
+	if s.PushedBefore, err = dateFlag(fs, "pushed-before"); err != nil {
+		return nil, err
+	}
+	if s.PushedAfter, err = dateFlag(fs, "pushed-after"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dateFlag parses a date like '2024-01-31' or a RFC 3339 timestamp.
+func dateFlag(fs *pflag.FlagSet, name string) (time.Time, error) {
+	s, err := fs.GetString(name)
+	if err != nil || s == "" {
+		return time.Time{}, err
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing '--%s': invalid date %q, expected YYYY-MM-DD or RFC 3339", name, s)
+	}
+	return t, nil
+}
@@ -0,0 +1,70 @@
+package selector
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromFlags(t *testing.T) {
+	testCases := []struct {
+		args    []string
+		want    func(*Selector) bool
+		wantErr bool
+	}{
+		{ // defaults
+			want: func(s *Selector) bool {
+				return s.Archived == Exclude && s.Template == Any && len(s.Include) == 0
+			},
+		},
+		{ // patterns
+			args: []string{"--include", "service-*", "--include", "/^web-/", "--exclude", "service-legacy"},
+			want: func(s *Selector) bool {
+				return len(s.Include) == 2 && len(s.Exclude) == 1
+			},
+		},
+		{ // patterns with commas
+			args: []string{"--include", "/^(foo|bar){1,3}$/", "--exclude", "{a,b}*"},
+			want: func(s *Selector) bool {
+				return len(s.Include) == 1 && s.Include[0].String() == "/^(foo|bar){1,3}$/" &&
+					len(s.Exclude) == 1 && s.Exclude[0].String() == "{a,b}*"
+			},
+		},
+		{ // dates
+			args: []string{"--pushed-after", "2024-01-31", "--pushed-before", "2024-03-01T12:00:00Z"},
+			want: func(s *Selector) bool {
+				return s.PushedAfter.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) &&
+					s.PushedBefore.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+			},
+		},
+		{ // archived only
+			args: []string{"--archived", "only"},
+			want: func(s *Selector) bool { return s.Archived == Only },
+		},
+		{args: []string{"--include", "/(/"}, wantErr: true},             // invalid pattern
+		{args: []string{"--visibility", "secret"}, wantErr: true},       // invalid visibility
+		{args: []string{"--archived", "yes"}, wantErr: true},            // invalid archived
+		{args: []string{"--pushed-before", "yesterday"}, wantErr: true}, // invalid date
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			AddFlags(fs)
+			assert.NoError(fs.Parse(tc.args))
+
+			s, err := FromFlags(fs)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.True(tc.want(s))
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Package selector selects repositories by name, owner and metadata for commands
+// iterating over many repositories.
+package selector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+)
+
+// Pattern matches repository names. It is either a glob pattern like 'service-*' or a
+// regular expression enclosed in slashes like '/^service-(api|web)$/'. Patterns containing
+// a slash are matched against the full name owner/name, all others against the name.
+// Glob patterns match case-insensitively, like GitHub treats repository names.
+type Pattern struct {
+	raw      string
+	glob     string
+	re       *regexp.Regexp
+	fullName bool
+}
+
+// ParsePattern parses a glob or /regex/ pattern.
+func ParsePattern(s string) (Pattern, error) {
+	if s == "" {
+		return Pattern{}, fmt.Errorf("empty pattern")
+	}
+	if len(s) > 1 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		expr := s[1 : len(s)-1]
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Pattern{}, fmt.Errorf("invalid regular expression %q: %w", expr, err)
+		}
+		return Pattern{raw: s, re: re, fullName: strings.Contains(expr, "/")}, nil
+	}
+	glob := strings.ToLower(s)
+	if _, err := path.Match(glob, ""); err != nil {
+		return Pattern{}, fmt.Errorf("invalid glob pattern %q: %w", s, err)
+	}
+	return Pattern{raw: s, glob: glob, fullName: strings.Contains(s, "/")}, nil
+}
+
+// ParsePatterns parses multiple patterns.
+func ParsePatterns(patterns []string) ([]Pattern, error) {
+	var parsed []Pattern
+	for _, s := range patterns {
+		p, err := ParsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// Literal returns a pattern matching exactly the given name, ignoring case.
+func Literal(name string) Pattern {
+	return Pattern{raw: name, re: regexp.MustCompile("(?i)^" + regexp.QuoteMeta(name) + "$"), fullName: strings.Contains(name, "/")}
+}
+
+// String returns the pattern as it was given.
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// Match reports whether the repository matches the pattern.
+func (p Pattern) Match(repo *github.Repository) bool {
+	name := repo.GetName()
+	if p.fullName {
+		name = repo.GetFullName()
+		if name == "" {
+			name = repo.GetOwner().GetLogin() + "/" + repo.GetName()
+		}
+	}
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	ok, _ := path.Match(p.glob, strings.ToLower(name))
+	return ok
+}
+
+func matchAny(repo *github.Repository, patterns []Pattern) bool {
+	for _, p := range patterns {
+		if p.Match(repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter decides whether repositories with a boolean property are selected.
+type Filter string
+
+const (
+	// Any selects repositories regardless of the property.
+	Any Filter = ""
+	// Exclude skips repositories with the property.
+	Exclude Filter = "exclude"
+	// Only selects only repositories with the property.
+	Only Filter = "only"
+)
+
+// ParseFilter parses 'include', 'exclude' or 'only'.
+func ParseFilter(s string) (Filter, error) {
+	switch strings.ToLower(s) {
+	case "include", "":
+		return Any, nil
+	case string(Exclude):
+		return Exclude, nil
+	case string(Only):
+		return Only, nil
+	default:
+		return "", fmt.Errorf("invalid filter %q, expected include, exclude or only", s)
+	}
+}
+
+func (f Filter) match(value bool) bool {
+	switch f {
+	case Exclude:
+		return !value
+	case Only:
+		return value
+	default:
+		return true
+	}
+}
+
+// Selector selects repositories. Every criterion that is set must match,
+// the zero value selects all repositories.
+type Selector struct {
+	// Include selects the repositories matching one of the patterns.
+	Include []Pattern
+	// Exclude skips the repositories matching one of the patterns.
+	Exclude []Pattern
+	// Owners selects the repositories owned by one of the users or organizations.
+	Owners []string
+	// Topics selects the repositories with at least one of the topics.
+	Topics []string
+	// Languages selects the repositories with one of the primary languages.
+	Languages []string
+	// Visibility selects the repositories with the visibility, one of public, private or internal.
+	Visibility string
+
+	Archived Filter
+	Template Filter
+
+	// PushedBefore and PushedAfter select the repositories by their last push.
+	PushedBefore time.Time
+	PushedAfter  time.Time
+}
+
+// Match reports whether the repository is selected.
+func (s *Selector) Match(repo *github.Repository) bool {
+	if len(s.Include) > 0 && !matchAny(repo, s.Include) {
+		return false
+	}
+	if matchAny(repo, s.Exclude) {
+		return false
+	}
+	if len(s.Owners) > 0 && !containsFold(s.Owners, repo.GetOwner().GetLogin()) {
+		return false
+	}
+	if len(s.Topics) > 0 && !containsAnyFold(s.Topics, repo.Topics) {
+		return false
+	}
+	if len(s.Languages) > 0 && !containsFold(s.Languages, repo.GetLanguage()) {
+		return false
+	}
+	if s.Visibility != "" && !strings.EqualFold(s.Visibility, visibility(repo)) {
+		return false
+	}
+	if !s.Archived.match(repo.GetArchived()) || !s.Template.match(repo.GetIsTemplate()) {
+		return false
+	}
+	pushedAt := repo.GetPushedAt().Time
+	if !s.PushedBefore.IsZero() && !pushedAt.Before(s.PushedBefore) {
+		return false
+	}
+	if !s.PushedAfter.IsZero() && !pushedAt.After(s.PushedAfter) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the selected repositories.
+func (s *Selector) Filter(repos []*github.Repository) []*github.Repository {
+	var selected []*github.Repository
+	for _, repo := range repos {
+		if s.Match(repo) {
+			selected = append(selected, repo)
+		}
+	}
+	return selected
+}
+
+// visibility returns the visibility of the repository. Older GitHub Enterprise Server
+// versions don't return the visibility, only whether the repository is private.
+func visibility(repo *github.Repository) string {
+	if v := repo.GetVisibility(); v != "" {
+		return v
+	}
+	if repo.GetPrivate() {
+		return "private"
+	}
+	return "public"
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyFold(list, values []string) bool {
+	for _, v := range values {
+		if containsFold(list, v) {
+			return true
+		}
+	}
+	return false
+}
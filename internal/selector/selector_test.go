@@ -0,0 +1,162 @@
+package selector
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternMatch(t *testing.T) {
+	repo := &github.Repository{
+		Name:     github.String("Service-API"),
+		FullName: github.String("myorg/Service-API"),
+		Owner:    &github.User{Login: github.String("myorg")},
+	}
+
+	testCases := []struct {
+		pattern   string
+		wantMatch bool
+		wantErr   bool
+	}{
+		{pattern: "service-api", wantMatch: true},           // exact name
+		{pattern: "service-*", wantMatch: true},             // glob
+		{pattern: "web-*", wantMatch: false},                // glob no match
+		{pattern: "myorg/service-*", wantMatch: true},       // glob full name
+		{pattern: "other/*", wantMatch: false},              // glob other owner
+		{pattern: "/^Service-(API|Web)$/", wantMatch: true}, // regex
+		{pattern: "/^service-api$/", wantMatch: false},      // regex is case-sensitive
+		{pattern: "/(?i)^service-api$/", wantMatch: true},   // regex case-insensitive
+		{pattern: "/^myorg/.*-API$/", wantMatch: true},      // regex full name
+		{pattern: "service-[", wantErr: true},               // invalid glob
+		{pattern: "/service-(/", wantErr: true},             // invalid regex
+		{pattern: "", wantErr: true},                        // empty
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, err := ParsePattern(tc.pattern)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantMatch, p.Match(repo))
+		})
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	assert := assert.New(t)
+
+	repo := &github.Repository{Name: github.String("ghh.go")}
+	assert.True(Literal("GHH.go").Match(repo))
+	assert.False(Literal("ghh.*").Match(repo))
+	assert.False(Literal("ghh").Match(repo))
+}
+
+func TestSelectorMatch(t *testing.T) {
+	pushed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &github.Repository{
+		Name:       github.String("ghh"),
+		FullName:   github.String("katexochen/ghh"),
+		Owner:      &github.User{Login: github.String("katexochen")},
+		Topics:     []string{"cli", "github"},
+		Language:   github.String("Go"),
+		Visibility: github.String("public"),
+		Archived:   github.Bool(false),
+		IsTemplate: github.Bool(true),
+		PushedAt:   &github.Timestamp{Time: pushed},
+	}
+
+	testCases := []struct {
+		selector  Selector
+		wantMatch bool
+	}{
+		{wantMatch: true}, // zero value
+		{selector: Selector{Include: mustPatterns(t, "foo", "g*")}, wantMatch: true}, // include
+		{selector: Selector{Include: mustPatterns(t, "foo")}, wantMatch: false},      // include no match
+		{selector: Selector{Exclude: mustPatterns(t, "/^gh/")}, wantMatch: false},    // exclude
+		{selector: Selector{Owners: []string{"KATEXOCHEN"}}, wantMatch: true},        // owner
+		{selector: Selector{Owners: []string{"myorg"}}, wantMatch: false},            // other owner
+		{selector: Selector{Topics: []string{"security", "CLI"}}, wantMatch: true},   // topic
+		{selector: Selector{Topics: []string{"security"}}, wantMatch: false},         // missing topic
+		{selector: Selector{Languages: []string{"go"}}, wantMatch: true},             // language
+		{selector: Selector{Languages: []string{"rust"}}, wantMatch: false},          // other language
+		{selector: Selector{Visibility: "public"}, wantMatch: true},                  // visibility
+		{selector: Selector{Visibility: "private"}, wantMatch: false},                // other visibility
+		{selector: Selector{Archived: Exclude}, wantMatch: true},                     // archived excluded
+		{selector: Selector{Archived: Only}, wantMatch: false},                       // only archived
+		{selector: Selector{Template: Only}, wantMatch: true},                        // only templates
+		{selector: Selector{Template: Exclude}, wantMatch: false},                    // templates excluded
+		{selector: Selector{PushedBefore: pushed.Add(time.Hour)}, wantMatch: true},   // pushed before
+		{selector: Selector{PushedBefore: pushed}, wantMatch: false},                 // not pushed before
+		{selector: Selector{PushedAfter: pushed.Add(-time.Hour)}, wantMatch: true},   // pushed after
+		{selector: Selector{PushedAfter: pushed}, wantMatch: false},                  // not pushed after
+		{ // all criteria
+			selector: Selector{
+				Include:   mustPatterns(t, "katexochen/*"),
+				Owners:    []string{"katexochen"},
+				Topics:    []string{"cli"},
+				Languages: []string{"Go"},
+				Archived:  Exclude,
+			},
+			wantMatch: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.wantMatch, tc.selector.Match(repo))
+		})
+	}
+}
+
+func TestSelectorVisibilityFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &Selector{Visibility: "private"}
+	assert.True(s.Match(&github.Repository{Private: github.Bool(true)}))
+	assert.False(s.Match(&github.Repository{Private: github.Bool(false)}))
+}
+
+func TestParseFilter(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    Filter
+		wantErr bool
+	}{
+		{value: "include", want: Any},     // include
+		{value: "", want: Any},            // empty
+		{value: "Exclude", want: Exclude}, // exclude
+		{value: "only", want: Only},       // only
+		{value: "yes", wantErr: true},     // invalid
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert := assert.New(t)
+
+			f, err := ParseFilter(tc.value)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, f)
+		})
+	}
+}
+
+func mustPatterns(t *testing.T, patterns ...string) []Pattern {
+	t.Helper()
+	parsed, err := ParsePatterns(patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
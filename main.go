@@ -33,20 +33,38 @@ func run() error {
 
 func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
-		Use:              "ghh",
-		Short:            "GitHub Helper CLI",
-		Version:          version,
-		PersistentPreRun: preRunRoot,
+		Use:               "ghh",
+		Short:             "GitHub Helper CLI",
+		Version:           version,
+		PersistentPreRunE: preRunRoot,
 	}
 
 	rootCmd.SetOut(os.Stdout)
 	rootCmd.AddCommand(
 		cmd.NewDeleteAllRunsCmd(),
+		cmd.NewPruneArtifactsCmd(),
+		cmd.NewPruneCachesCmd(),
+		cmd.NewRunsCmd(),
 		cmd.NewCreateProjectIssueCmd(),
 		cmd.NewSyncForksCmd(),
 		cmd.NewSetAuthCmd(),
+		cmd.NewAuthCmd(),
+		cmd.NewLoginCmd(),
 	)
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Print what would be changed without changing anything")
+	rootCmd.PersistentFlags().String("host", "", "GitHub host to use, e.g. a GitHub Enterprise Server instance (env GHH_HOST)")
+	rootCmd.PersistentFlags().String("profile", "", "Name of the auth profile to use, takes precedence over --host (env GHH_PROFILE)")
+	rootCmd.PersistentFlags().String(
+		"token-source",
+		"",
+		"Only read the token from this source, one of ghh-env, config, env, gh, git-credential",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"remote",
+		[]string{"origin", "upstream"},
+		"Git remotes to detect the repository from, in order of preference",
+	)
 	rootCmd.InitDefaultVersionFlag()
 	rootCmd.SetVersionTemplate(
 		fmt.Sprintf("ghh - GitHub helper CLI\n\nversion   %s\ncommit    %s\nbuilt at  %s\n", version, commit, date),
@@ -78,6 +96,7 @@ func signalContext(ctx context.Context, sig os.Signal) (context.Context, context
 	return sigCtx, cancelFunc
 }
 
-func preRunRoot(cmd *cobra.Command, _ []string) {
-	cmd.SilenceUsage = true
+func preRunRoot(c *cobra.Command, _ []string) error {
+	c.SilenceUsage = true
+	return cmd.ApplyDefaults(c)
 }